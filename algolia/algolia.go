@@ -0,0 +1,195 @@
+package algolia
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client for the HN Search API: https://hn.algolia.com/api
+
+const (
+	DefaultBaseURL  = "https://hn.algolia.com/api/v1"
+	DefaultPageSize = 50
+	dateLayout      = "2006-01-02"
+)
+
+type Query struct {
+	Text      string
+	ByDate    bool      // sort by date (search_by_date) instead of relevance (search)
+	Type      string    // "story", "comment" or empty for both
+	MinPoints int       // 0 for no filter
+	After     time.Time // zero for no filter
+	Before    time.Time // zero for no filter
+}
+
+// ParseQuery reads a query typed in the search prompt. Besides the search
+// text, it understands the following filters:
+//
+//	type:story|comment  points:N  after:YYYY-MM-DD  before:YYYY-MM-DD  sort:date|relevance
+func ParseQuery(s string) (Query, error) {
+	var q Query
+	var text []string
+	for _, word := range strings.Fields(s) {
+		sep := strings.Index(word, ":")
+		if sep < 0 {
+			text = append(text, word)
+			continue
+		}
+
+		key, value := strings.ToLower(word[:sep]), word[sep+1:]
+		var err error
+		switch key {
+		case "type":
+			value = strings.ToLower(value)
+			if value != "story" && value != "comment" {
+				return q, fmt.Errorf("type must be story or comment, got %q", value)
+			}
+			q.Type = value
+		case "points":
+			q.MinPoints, err = strconv.Atoi(strings.TrimLeft(value, ">="))
+			if err != nil {
+				return q, fmt.Errorf("points must be a number, got %q", value)
+			}
+		case "after":
+			q.After, err = time.Parse(dateLayout, value)
+			if err != nil {
+				return q, fmt.Errorf("after must be a YYYY-MM-DD date, got %q", value)
+			}
+		case "before":
+			q.Before, err = time.Parse(dateLayout, value)
+			if err != nil {
+				return q, fmt.Errorf("before must be a YYYY-MM-DD date, got %q", value)
+			}
+		case "sort":
+			switch strings.ToLower(value) {
+			case "date":
+				q.ByDate = true
+			case "relevance":
+				q.ByDate = false
+			default:
+				return q, fmt.Errorf("sort must be date or relevance, got %q", value)
+			}
+		default:
+			// not a filter (e.g.: an URL)
+			text = append(text, word)
+		}
+	}
+	q.Text = strings.Join(text, " ")
+	return q, nil
+}
+
+// Short description of the query, e.g.: "rust (stories, 100+ points)".
+func (q Query) String() string {
+	var filters []string
+	if len(q.Type) > 0 {
+		filters = append(filters, q.Type+" only")
+	}
+	if q.MinPoints > 0 {
+		filters = append(filters, fmt.Sprintf("%d+ points", q.MinPoints))
+	}
+	if !q.After.IsZero() {
+		filters = append(filters, "after "+q.After.Format(dateLayout))
+	}
+	if !q.Before.IsZero() {
+		filters = append(filters, "before "+q.Before.Format(dateLayout))
+	}
+	if q.ByDate {
+		filters = append(filters, "by date")
+	}
+
+	ret := fmt.Sprintf("%q", q.Text)
+	if len(filters) > 0 {
+		ret += " (" + strings.Join(filters, ", ") + ")"
+	}
+	return ret
+}
+
+func (q Query) values() url.Values {
+	v := url.Values{}
+	v.Set("query", q.Text)
+	v.Set("hitsPerPage", strconv.Itoa(DefaultPageSize))
+	if len(q.Type) > 0 {
+		v.Set("tags", q.Type)
+	}
+
+	var numeric []string
+	if q.MinPoints > 0 {
+		numeric = append(numeric, "points>="+strconv.Itoa(q.MinPoints))
+	}
+	if !q.After.IsZero() {
+		numeric = append(numeric, "created_at_i>="+strconv.FormatInt(q.After.Unix(), 10))
+	}
+	if !q.Before.IsZero() {
+		numeric = append(numeric, "created_at_i<"+strconv.FormatInt(q.Before.Unix(), 10))
+	}
+	if len(numeric) > 0 {
+		v.Set("numericFilters", strings.Join(numeric, ","))
+	}
+	return v
+}
+
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// New creates a client for the search API at baseURL (DefaultBaseURL if empty).
+func New(baseURL string, timeout time.Duration) *Client {
+	if len(baseURL) == 0 {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Search returns the ids of the matching items, so they can be loaded through
+// the regular API.
+func (c *Client) Search(ctx context.Context, q Query) ([]int, error) {
+	endpoint := "/search"
+	if q.ByDate {
+		endpoint = "/search_by_date"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.baseURL+endpoint+"?"+q.values().Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search: %s", res.Status)
+	}
+	var data struct {
+		Hits []struct {
+			ObjectID string `json:"objectID"`
+		} `json:"hits"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(data.Hits))
+	for _, hit := range data.Hits {
+		id, err := strconv.Atoi(hit.ObjectID)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,86 @@
+package algolia
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery("rust type:Story points:>=100 after:2021-01-02 sort:date https://x.y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Query{
+		Text:      "rust https://x.y",
+		ByDate:    true,
+		Type:      "story",
+		MinPoints: 100,
+		After:     time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("ParseQuery() = %+v, want %+v", q, want)
+	}
+	if got := q.String(); got != `"rust https://x.y" (story only, 100+ points, after 2021-01-02, by date)` {
+		t.Errorf("String() = %s", got)
+	}
+
+	for _, s := range []string{"type:job", "points:many", "after:yesterday", "before:2021", "sort:votes"} {
+		if _, err := ParseQuery(s); err == nil {
+			t.Errorf("ParseQuery(%q) succeeded", s)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	var path string
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.Query()
+		_, _ = w.Write([]byte(`{"hits":[{"objectID":"3"},{"objectID":"oops"},{"objectID":"1"}]}`))
+	}))
+	defer srv.Close()
+	c := New(srv.URL+"/", time.Second)
+
+	q := Query{Text: "go", ByDate: true, Type: "comment", MinPoints: 5,
+		Before: time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)}
+	ids, err := c.Search(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int{3, 1}) {
+		t.Errorf("Search() = %v, want [3 1] (hits that aren't ids are skipped)", ids)
+	}
+	if path != "/search_by_date" {
+		t.Errorf("requested %s, want /search_by_date", path)
+	}
+	wantQuery := url.Values{
+		"query":          {"go"},
+		"hitsPerPage":    {"50"},
+		"tags":           {"comment"},
+		"numericFilters": {"points>=5,created_at_i<1643673600"},
+	}
+	if !reflect.DeepEqual(query, wantQuery) {
+		t.Errorf("query = %v, want %v", query, wantQuery)
+	}
+
+	if _, err = c.Search(context.Background(), Query{Text: "go"}); err != nil || path != "/search" {
+		t.Errorf("Search() by relevance requested %s (%v), want /search", path, err)
+	}
+}
+
+func TestSearchStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL, time.Second).Search(context.Background(), Query{Text: "go"})
+	if err == nil || err.Error() != "search: 429 Too Many Requests" {
+		t.Errorf("Search() error = %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package article
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// The reader mode shows the article a story links to, without leaving the
+// terminal: the page is fetched, its main content is extracted (see Extract)
+// and the result goes through the same markdown pipeline as the posts.
+
+// Max size of the pages fetched (bigger ones are cut short).
+const maxPageSize = 5 << 20
+
+// Fetcher gets the HTML of web pages. It's an interface so the reader can be
+// pointed at something else (e.g.: a local server).
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) ([]byte, error)
+}
+
+type httpFetcher struct {
+	http *http.Client
+}
+
+// NewFetcher creates a fetcher that GETs pages over HTTP.
+func NewFetcher(timeout time.Duration) Fetcher {
+	return &httpFetcher{
+		http: &http.Client{Timeout: timeout},
+	}
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// some sites only answer to browsers
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; HackerReader)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	res, err := f.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, res.Status)
+	}
+	if contentType := res.Header.Get("Content-Type"); len(contentType) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return nil, fmt.Errorf("not a web page (%s)", mediaType)
+		}
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxPageSize))
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Fetch gets the page and extracts its article.
+func Fetch(ctx context.Context, fetcher Fetcher, url string) (Article, error) {
+	page, err := fetcher.Fetch(ctx, url)
+	if err != nil {
+		return Article{}, err
+	}
+	return Extract(bytes.NewReader(page), url)
+}
@@ -0,0 +1,115 @@
+package article
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const page = `<html><head><title>The Title</title><meta name="author" content="Jane Doe"></head><body>
+<nav><a href="/">Home</a> <a href="/about">About us, and more, and more, and more</a></nav>
+<div class="sidebar"><p>Subscribe to our newsletter, it is great, really, trust us.</p></div>
+<div class="post-content">
+  <h1>The Title</h1>
+  <p>The first paragraph of the article, with commas, and enough words to score.</p>
+  <p>The second one, with a <a href="/rel">relative link</a> and an <img src="img.png">, of course.</p>
+</div>
+<div class="comments"><p>A comment, which is long enough, but shouldn't be part of it.</p></div>
+<footer><p>Copyright, all rights reserved, lorem ipsum, dolor sit amet.</p></footer>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+	a, err := Extract(strings.NewReader(page), "https://example.com/posts/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "The Title" || a.Byline != "Jane Doe" || a.Url != "https://example.com/posts/1" {
+		t.Errorf("Extract() = %q by %q at %q", a.Title, a.Byline, a.Url)
+	}
+	for _, want := range []string{"first paragraph", `href="https://example.com/rel"`,
+		`src="https://example.com/posts/img.png"`} {
+		if !strings.Contains(a.Content, want) {
+			t.Errorf("content is missing %q:\n%s", want, a.Content)
+		}
+	}
+	for _, unwanted := range []string{"<h1>", "Home", "newsletter", "A comment", "Copyright"} {
+		if strings.Contains(a.Content, unwanted) {
+			t.Errorf("content has %q:\n%s", unwanted, a.Content)
+		}
+	}
+}
+
+func TestExtractArticle(t *testing.T) {
+	// a paragraph is only a part of the <article>
+	html := `<html><body><article><h2>Intro</h2><div><p>A paragraph, long enough, with commas, to score.</p></div>
+<figure>a figure</figure></article></body></html>`
+	a, err := Extract(strings.NewReader(html), "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(a.Content, "<article>") || !strings.Contains(a.Content, "a figure") {
+		t.Errorf("content isn't the whole <article>:\n%s", a.Content)
+	}
+}
+
+func TestExtractNoContent(t *testing.T) {
+	_, err := Extract(strings.NewReader(`<html><body><nav><a href="/">Home</a></nav></body></html>`), "")
+	if !errors.Is(err, ErrNoContent) {
+		t.Errorf("Extract() error = %v, want ErrNoContent", err)
+	}
+}
+
+func serve(t *testing.T, contentType string, body []byte) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(contentType) > 0 {
+			w.Header().Set("Content-Type", contentType)
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestFetch(t *testing.T) {
+	url := serve(t, "text/html; charset=utf-8", []byte(page))
+	a, err := Fetch(context.Background(), NewFetcher(time.Second), url+"/posts/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "The Title" || !strings.Contains(a.Content, `href="`+url+`/rel"`) {
+		t.Errorf("Fetch() = %q:\n%s", a.Title, a.Content)
+	}
+}
+
+func TestFetchNotHtml(t *testing.T) {
+	url := serve(t, "application/pdf", []byte("%PDF-1.4"))
+	_, err := NewFetcher(time.Second).Fetch(context.Background(), url)
+	if err == nil || err.Error() != "not a web page (application/pdf)" {
+		t.Errorf("Fetch() error = %v", err)
+	}
+}
+
+func TestFetchStatus(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	if _, err := NewFetcher(time.Second).Fetch(context.Background(), srv.URL); err == nil {
+		t.Error("Fetch() succeeded on a 404")
+	}
+}
+
+func TestFetchLimit(t *testing.T) {
+	url := serve(t, "text/html", bytes.Repeat([]byte("<p>lorem ipsum</p>"), maxPageSize/10))
+	body, err := NewFetcher(time.Second).Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != maxPageSize {
+		t.Errorf("Fetch() read %d bytes, want %d", len(body), maxPageSize)
+	}
+}
@@ -0,0 +1,212 @@
+package article
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Article is the main content of a web page.
+type Article struct {
+	Title   string
+	Byline  string
+	Url     string
+	Content string // HTML (with absolute links)
+}
+
+var ErrNoContent = errors.New("couldn't find the article in the page")
+
+var (
+	// never part of the article
+	junkSelector = "script, style, noscript, iframe, object, embed, svg, canvas, form, button, input, " +
+		"select, textarea, nav, aside, footer, header, dialog, [role=navigation], [role=banner], " +
+		"[role=complementary], [role=dialog], [aria-hidden=true], [hidden]"
+	// classes/ids that say something about the content
+	positiveRe = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negativeRe = regexp.MustCompile(`(?i)comment|combx|community|sidebar|side-bar|footer|footnote|masthead|` +
+		`menu|nav|share|social|sponsor|promo|related|recommend|subscribe|newsletter|signup|cookie|banner|` +
+		`popup|modal|advert|\bads?\b|breadcrumb|pagination|widget|meta|byline|author|tags`)
+	unlikelyRe = regexp.MustCompile(`(?i)comment|sidebar|footer|menu|nav|share|social|promo|related|` +
+		`subscribe|newsletter|cookie|popup|modal|advert|breadcrumb|widget`)
+	spacesRe = regexp.MustCompile(`\s+`)
+)
+
+// Finds the main content of the page, readability style: the junk is
+// removed, every paragraph scores points for the elements containing it (by
+// its length and commas) and the best scoring element wins. Elements whose
+// class/id look like content get a boost, and the ones that look like
+// comments, sidebars, ads, ... a penalty.
+func Extract(page io.Reader, pageUrl string) (Article, error) {
+	doc, err := goquery.NewDocumentFromReader(page)
+	if err != nil {
+		return Article{}, err
+	}
+	base, _ := url.Parse(pageUrl)
+
+	a := Article{
+		Url:    pageUrl,
+		Title:  title(doc),
+		Byline: byline(doc),
+	}
+
+	doc.Find(junkSelector).Remove()
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		if isUnlikely(s) {
+			s.Remove()
+		}
+	})
+
+	best := bestCandidate(doc)
+	if best == nil {
+		return a, ErrNoContent
+	}
+	// images and links work outside of the page too
+	best.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		s.SetAttr("href", absolute(base, href))
+	})
+	best.Find("img[src]").Each(func(_ int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		s.SetAttr("src", absolute(base, src))
+	})
+	// the title is shown on its own
+	best.Find("h1").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return normalize(s.Text()) == normalize(a.Title)
+	}).Remove()
+
+	a.Content, err = goquery.OuterHtml(best)
+	if err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+func title(doc *goquery.Document) string {
+	if t, exists := doc.Find(`meta[property="og:title"]`).Attr("content"); exists && len(strings.TrimSpace(t)) > 0 {
+		return normalize(t)
+	}
+	if t := normalize(doc.Find("title").First().Text()); len(t) > 0 {
+		return t
+	}
+	return normalize(doc.Find("h1").First().Text())
+}
+
+func byline(doc *goquery.Document) string {
+	if author, exists := doc.Find(`meta[name="author"]`).Attr("content"); exists {
+		return normalize(author)
+	}
+	return normalize(doc.Find(`[rel=author], [itemprop=author]`).First().Text())
+}
+
+// Elements that look like something else than content (unless they are big
+// containers, e.g.: a <body class="has-sidebar">)
+func isUnlikely(s *goquery.Selection) bool {
+	switch goquery.NodeName(s) {
+	case "html", "body", "article", "main", "a":
+		return false
+	}
+	class, _ := s.Attr("class")
+	id, _ := s.Attr("id")
+	match := class + " " + id
+	return unlikelyRe.MatchString(match) && !positiveRe.MatchString(match)
+}
+
+// Points for the class/id of the element.
+func classWeight(s *goquery.Selection) float64 {
+	weight := 0.0
+	for _, attr := range []string{"class", "id"} {
+		v, exists := s.Attr(attr)
+		if !exists {
+			continue
+		}
+		if negativeRe.MatchString(v) {
+			weight -= 25
+		}
+		if positiveRe.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// Share of the text of the element that is inside links (menus are mostly
+// links, articles aren't).
+func linkDensity(s *goquery.Selection) float64 {
+	textLen := len(normalize(s.Text()))
+	if textLen == 0 {
+		return 0
+	}
+	linkLen := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLen += len(normalize(a.Text()))
+	})
+	return float64(linkLen) / float64(textLen)
+}
+
+func bestCandidate(doc *goquery.Document) *goquery.Selection {
+	scores := make(map[*html.Node]float64)
+	var candidates []*goquery.Selection
+	addScore := func(s *goquery.Selection, points float64) {
+		if s.Length() == 0 {
+			return
+		}
+		node := s.Get(0)
+		if _, scored := scores[node]; !scored {
+			scores[node] = classWeight(s)
+			candidates = append(candidates, s)
+		}
+		scores[node] += points
+	}
+
+	doc.Find("p, pre, td, blockquote, li").Each(func(_ int, p *goquery.Selection) {
+		text := normalize(p.Text())
+		if len(text) < 25 {
+			return
+		}
+		points := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		addScore(p.Parent(), points)
+		addScore(p.Parent().Parent(), points/2)
+	})
+
+	var best *goquery.Selection
+	bestScore := 0.0
+	for _, s := range candidates {
+		score := scores[s.Get(0)] * (1 - linkDensity(s))
+		if best == nil || score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	if best == nil {
+		// no paragraphs => the <article> (or <main>), if there's one
+		if s := doc.Find("article, main").First(); s.Length() > 0 && len(normalize(s.Text())) > 0 {
+			return s
+		}
+		return nil
+	}
+	// inside an <article> => the candidate might only be a part of it
+	if article := best.Closest("article"); article.Length() > 0 {
+		return article
+	}
+	return best
+}
+
+func absolute(base *url.URL, ref string) string {
+	if base == nil {
+		return ref
+	}
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+func normalize(s string) string {
+	return strings.TrimSpace(spacesRe.ReplaceAllString(s, " "))
+}
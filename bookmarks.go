@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"hackerreader/bookmarks"
+	"hackerreader/export"
+	"hackerreader/hn"
+	"hackerreader/posts"
+	"hackerreader/scheduler"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Bookmarks: posts (stories and comments alike) saved for later. They are the
+// "Saved" feed, cycled through with the API's feeds. The hidden posts are kept
+// with them, so they stay hidden.
+
+const savedFeed hn.Feed = "saved"
+
+// The feeds, in the order they are cycled through.
+var feedOrder = append(append([]hn.Feed(nil), hn.Feeds...), savedFeed)
+
+func feedTitle(feed hn.Feed) string {
+	if feed == savedFeed {
+		return "Saved"
+	}
+	return feed.Title()
+}
+
+// Sets what the bookmarks say about the post (when it arrives).
+func (m *model) applyBookmarks(st *posts.Post) {
+	mark, saved := m.bookmarks.Get(st.Id)
+	st.Saved = saved
+	st.Tags = mark.Tags
+	st.Hidden = m.bookmarks.Hidden(st.Id)
+}
+
+// The stories of the Saved feed. The saved posts that aren't loaded are shown
+// as they were saved while they're fetched (for good, offline).
+func (m *model) savedStories() []int {
+	for _, mark := range m.bookmarks.All() {
+		if st := m.loadPost(mark.Post.Id, scheduler.Background); !st.IsLoaded() {
+			m.setPost(posts.FromSnapshot(mark.Post, m.spinner))
+		}
+	}
+	return m.bookmarks.Ids()
+}
+
+// Keeps the Saved feed in sync with the bookmarks (if it's shown: otherwise
+// it's done when switching to it).
+func (m *model) refreshSaved() {
+	if m.feed == savedFeed {
+		m.feeds[savedFeed] = m.savedStories()
+		m.setRootStories(m.feeds[savedFeed])
+	}
+}
+
+// Returns the post the bookmark keys act on: the hovered post or, if there's
+// none, the selected one.
+func (m *model) bookmarkTarget() (*posts.Post, bool) {
+	if stId, ok := m.hoveredId(); ok {
+		if st := m.getPost(stId); st.IsLoaded() {
+			return st, true
+		}
+	}
+	st := m.getPost(m.selected.Peek().(int))
+	return st, st.IsLoaded()
+}
+
+// Saves the post for later, or forgets it if it was saved.
+func (m *model) toggleBookmark(st *posts.Post) {
+	var err error
+	if st.Saved {
+		err = m.bookmarks.Remove(st.Id)
+		m.notice = "Removed from saved"
+	} else {
+		err = m.bookmarks.Add(st.Snapshot(m.nativeKids(st)))
+		m.notice = fmt.Sprintf("Saved (%s to tag it)", m.keys.Tag.Help().Key)
+	}
+	if err != nil {
+		m.notice = ""
+		m.err = fmt.Errorf("couldn't save the bookmarks: %w", err)
+		return
+	}
+	m.applyBookmarks(st)
+	m.refreshSaved()
+}
+
+// Hides/unhides the post, for good.
+func (m *model) toggleHidden(st *posts.Post) {
+	st.ToggleHidden()
+	if err := m.bookmarks.SetHidden(st.Id, st.Hidden); err != nil {
+		m.err = fmt.Errorf("couldn't save the hidden posts: %w", err)
+	}
+}
+
+// Opens the prompt to edit the tags of the post (saving it first if needed).
+func (m *model) openTags(st *posts.Post) {
+	if !st.Saved {
+		m.toggleBookmark(st)
+		if !st.Saved {
+			return
+		}
+	}
+	m.tagId = st.Id
+	m.openPrompt(tagPrompt, "Tags: ", "separated by spaces (empty to remove them all)")
+	m.prompt.SetValue(strings.Join(st.Tags, " "))
+}
+
+func (m *model) submitTags(value string) {
+	if err := m.bookmarks.SetTags(m.tagId, bookmarks.ParseTags(value)); err != nil {
+		m.prompt.SetError(fmt.Errorf("couldn't save the bookmarks: %w", err))
+		return
+	}
+	m.prompt.Close()
+	if st, exists := m.stories[m.tagId]; exists {
+		m.applyBookmarks(st)
+	}
+}
+
+// Writes the bookmarks to a file browsers can import.
+func exportBookmarks(path string, marks []bookmarks.Bookmark) tea.Cmd {
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		f, err := os.Create(path)
+		if err == nil {
+			err = export.Bookmarks(f, marks)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			return exportMsg{path: path, err: err, retry: cmd}
+		}
+		return exportMsg{path: path, posts: len(marks)}
+	}
+	return cmd
+}
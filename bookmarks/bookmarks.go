@@ -0,0 +1,209 @@
+package bookmarks
+
+import (
+	"encoding/json"
+	"errors"
+	"hackerreader/files"
+	"hackerreader/posts"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The posts saved for later (stories and comments alike), and the posts that
+// were hidden. Each bookmark keeps a snapshot of the post as it was saved, so
+// it can still be shown offline or once HN forgets it. It's saved as JSON on
+// every change, e.g.:
+//
+//	{
+//	  "bookmarks": [{"post": {"id": 30377425, ...}, "tags": ["go"], "saved": 1645000000}],
+//	  "hidden": [30377501]
+//	}
+//
+// A nil *Bookmarks is valid: it has no bookmarks and can't save any.
+
+type Bookmark struct {
+	Post  posts.Snapshot `json:"post"`
+	Tags  []string       `json:"tags,omitempty"`
+	Saved int64          `json:"saved"` // unix time it was saved at
+}
+
+// SavedAt is when the bookmark was saved.
+func (b *Bookmark) SavedAt() time.Time {
+	return time.Unix(b.Saved, 0)
+}
+
+type file struct {
+	Bookmarks []Bookmark `json:"bookmarks"`
+	Hidden    []int      `json:"hidden,omitempty"`
+}
+
+type Bookmarks struct {
+	path   string
+	marks  []Bookmark // the last saved first
+	hidden map[int]bool
+}
+
+// DefaultPath is $XDG_DATA_HOME/hackerreader/bookmarks.json
+// (~/.local/share/hackerreader/bookmarks.json if it isn't set).
+func DefaultPath() (string, error) {
+	return files.DataPath("bookmarks.json")
+}
+
+// Load reads the bookmarks at path. A missing file means no bookmarks yet
+// (it's created when the first one is saved).
+func Load(path string) (*Bookmarks, error) {
+	b := &Bookmarks{path: path, hidden: make(map[int]bool)}
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	b.marks = f.Bookmarks
+	for _, stId := range f.Hidden {
+		b.hidden[stId] = true
+	}
+	return b, nil
+}
+
+func (b *Bookmarks) Path() string {
+	if b == nil {
+		return ""
+	}
+	return b.path
+}
+
+func (b *Bookmarks) find(stId int) int {
+	if b == nil {
+		return -1
+	}
+	for i := range b.marks {
+		if b.marks[i].Post.Id == stId {
+			return i
+		}
+	}
+	return -1
+}
+
+// All returns the bookmarks, the last saved first.
+func (b *Bookmarks) All() []Bookmark {
+	if b == nil {
+		return nil
+	}
+	return append([]Bookmark(nil), b.marks...)
+}
+
+// Ids returns the ids of the saved posts, the last saved first.
+func (b *Bookmarks) Ids() []int {
+	if b == nil {
+		return nil
+	}
+	ids := make([]int, len(b.marks))
+	for i := range b.marks {
+		ids[i] = b.marks[i].Post.Id
+	}
+	return ids
+}
+
+// Get returns the bookmark of the post (if it's saved).
+func (b *Bookmarks) Get(stId int) (Bookmark, bool) {
+	i := b.find(stId)
+	if i < 0 {
+		return Bookmark{}, false
+	}
+	return b.marks[i], true
+}
+
+// Add saves the post (and the bookmarks). Saving it again only updates its
+// snapshot: the tags and the time it was saved are kept.
+func (b *Bookmarks) Add(s posts.Snapshot) error {
+	if b == nil {
+		return nil
+	}
+	if i := b.find(s.Id); i >= 0 {
+		b.marks[i].Post = s
+		return b.save()
+	}
+	mark := Bookmark{Post: s, Saved: time.Now().Unix()}
+	b.marks = append([]Bookmark{mark}, b.marks...)
+	return b.save()
+}
+
+// Remove deletes the bookmark of the post (and saves the bookmarks).
+func (b *Bookmarks) Remove(stId int) error {
+	i := b.find(stId)
+	if i < 0 {
+		return nil
+	}
+	b.marks = append(b.marks[:i], b.marks[i+1:]...)
+	return b.save()
+}
+
+// SetTags replaces the tags of the post's bookmark (and saves the bookmarks).
+func (b *Bookmarks) SetTags(stId int, tags []string) error {
+	i := b.find(stId)
+	if i < 0 {
+		return nil
+	}
+	b.marks[i].Tags = tags
+	return b.save()
+}
+
+// ParseTags splits the tags typed by the user (separated by spaces or
+// commas), dropping the repeated ones.
+func ParseTags(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}) {
+		tag = strings.TrimPrefix(tag, "#")
+		if len(tag) > 0 && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Hidden returns whether the post was hidden.
+func (b *Bookmarks) Hidden(stId int) bool {
+	return b != nil && b.hidden[stId]
+}
+
+// SetHidden records whether the post is hidden (and saves the bookmarks).
+func (b *Bookmarks) SetHidden(stId int, hidden bool) error {
+	if b == nil || b.hidden[stId] == hidden {
+		return nil
+	}
+	if hidden {
+		b.hidden[stId] = true
+	} else {
+		delete(b.hidden, stId)
+	}
+	return b.save()
+}
+
+func (b *Bookmarks) save() error {
+	f := file{Bookmarks: b.marks, Hidden: make([]int, 0, len(b.hidden))}
+	if f.Bookmarks == nil {
+		f.Bookmarks = []Bookmark{}
+	}
+	for stId := range b.hidden {
+		f.Hidden = append(f.Hidden, stId)
+	}
+	sort.Ints(f.Hidden)
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return files.WriteAtomic(b.path, append(data, '\n'))
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"encoding/json"
+	"github.com/buger/jsonparser"
+	"hackerreader/files"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// On-disk cache of the API responses, keyed by item id (or feed name).
+// The modification time of each file is when it was fetched. How long an
+// entry stays fresh depends on the item's type and age: recent stories change
+// all the time (score, comments), while old comments are pretty much frozen.
+// A nil *Cache is valid and caches nothing.
+
+const (
+	feedTTL       = 5 * time.Minute
+	storyTTL      = 15 * time.Minute
+	commentTTL    = time.Hour
+	frozenTTL     = 30 * 24 * time.Hour
+	frozenItemAge = 14 * 24 * time.Hour // HN stops accepting comments after 2 weeks
+)
+
+type Cache struct {
+	dir string
+}
+
+// DefaultDir is $XDG_CACHE_HOME/hackerreader (or the OS equivalent).
+func DefaultDir() (string, error) {
+	return files.CachePath("")
+}
+
+func New(dir string) (*Cache, error) {
+	for _, sub := range []string{"items", "feeds"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Cache{dir: dir}, nil
+}
+
+func (c *Cache) itemPath(id int) string {
+	return filepath.Join(c.dir, "items", strconv.Itoa(id)+".json")
+}
+
+func (c *Cache) feedPath(name string) string {
+	return filepath.Join(c.dir, "feeds", name+".json")
+}
+
+// How long the given item (raw JSON from the API) stays fresh.
+func itemTTL(raw []byte) time.Duration {
+	created, _ := jsonparser.GetInt(raw, "time")
+	if time.Since(time.Unix(created, 0)) > frozenItemAge {
+		return frozenTTL
+	}
+
+	itemType, _ := jsonparser.GetString(raw, "type")
+	switch itemType {
+	case "comment", "pollopt":
+		return commentTTL
+	default:
+		return storyTTL
+	}
+}
+
+// Returns the file's contents and whether it was written in the last ttl.
+func read(path string) ([]byte, time.Duration, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, false
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, 0, false
+	}
+	return data, time.Since(info.ModTime()), true
+}
+
+// Item returns the cached JSON of the item. Expired entries are only returned
+// if stale is true (e.g.: when offline).
+func (c *Cache) Item(id int, stale bool) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	raw, age, ok := read(c.itemPath(id))
+	if !ok || (!stale && age > itemTTL(raw)) {
+		return nil, false
+	}
+	return raw, true
+}
+
+func (c *Cache) PutItem(id int, raw []byte) error {
+	if c == nil {
+		return nil
+	}
+	return files.WriteAtomic(c.itemPath(id), raw)
+}
+
+// DeleteItem forgets the cached JSON of the item (e.g.: it's unusable).
+func (c *Cache) DeleteItem(id int) error {
+	if c == nil {
+		return nil
+	}
+	err := os.Remove(c.itemPath(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Feed returns the cached ids of the feed. Expired entries are only returned
+// if stale is true (e.g.: when offline).
+func (c *Cache) Feed(name string, stale bool) ([]int, bool) {
+	if c == nil {
+		return nil, false
+	}
+	raw, age, ok := read(c.feedPath(name))
+	if !ok || (!stale && age > feedTTL) {
+		return nil, false
+	}
+	var ids []int
+	if json.Unmarshal(raw, &ids) != nil {
+		return nil, false
+	}
+	return ids, true
+}
+
+func (c *Cache) PutFeed(name string, ids []int) error {
+	if c == nil {
+		return nil
+	}
+	raw, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return files.WriteAtomic(c.feedPath(name), raw)
+}
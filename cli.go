@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"hackerreader/cache"
+	"hackerreader/config"
+	"hackerreader/export"
+	"hackerreader/hn"
+	"hackerreader/thread"
+	"io"
+	"os"
+	"strconv"
+)
+
+// The subcommands print stories, threads or users to stdout (as text, JSON or
+// markdown) instead of starting the reader, e.g.: `hackerreader top -n 5`.
+
+const defaultStoryCount = 30
+
+const commandsUsage = `Commands (the reader starts when none is given):
+  top|new|best|ask|show|job [-n N]   print the stories of the feed
+  item <id> [--depth D]              print the item and its replies
+  user <name>                        print the user's profile
+  export <id> [-o file]              export the whole thread (markdown by
+                                     default, or as the file's extension says)
+Every command takes --format text|json|markdown|html (text by default).
+`
+
+type command struct {
+	cfg    config.Config
+	client hn.Client
+	cache  *cache.Cache // nil when caching is disabled
+	load   thread.Load
+	out    io.Writer
+}
+
+// Parses the flags of a subcommand. Unlike flag.Parse, flags can also come
+// after the arguments (e.g.: `item 42 --depth 1`), which are returned.
+func parseCommandFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// Runs the subcommand in args (the command line without the global flags).
+// Returns the exit code.
+func runCommand(cfg config.Config, c *cache.Cache, args []string) int {
+	client := hn.New(cfg.Http.ApiUrl, cfg.Http.Timeout)
+	cmd := &command{
+		cfg:    cfg,
+		client: client,
+		cache:  c,
+		load:   itemLoader(client, c, cfg.Cache.Offline),
+		out:    os.Stdout,
+	}
+
+	name := args[0]
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	format := fs.String("format", string(export.Text), "output format: text, json, markdown or html")
+	var run func(args []string, format export.Format) error
+
+	if feed, err := hn.ParseFeed(name); err == nil {
+		n := fs.Int("n", defaultStoryCount, "number of stories to print (negative => all)")
+		fs.Usage = commandUsage(fs, name+" [-n N]")
+		run = func(args []string, format export.Format) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unexpected arguments: %v", args)
+			}
+			return cmd.feed(feed, *n, format)
+		}
+	} else {
+		switch name {
+		case "item":
+			depth := fs.Int("depth", -1, "levels of replies to print (0 => none, negative => all)")
+			fs.Usage = commandUsage(fs, "item <id> [--depth D]")
+			run = func(args []string, format export.Format) error {
+				if len(args) != 1 {
+					return fmt.Errorf("expected an item id")
+				}
+				id, err := strconv.Atoi(args[0])
+				if err != nil || id <= 0 {
+					return fmt.Errorf("invalid item id %q", args[0])
+				}
+				return cmd.item(id, *depth, format)
+			}
+		case "user":
+			fs.Usage = commandUsage(fs, "user <name>")
+			run = func(args []string, format export.Format) error {
+				if len(args) != 1 {
+					return fmt.Errorf("expected a user name")
+				}
+				return cmd.user(args[0], format)
+			}
+		case "export":
+			output := fs.String("o", "", "file to write to (default stdout)")
+			fs.Usage = commandUsage(fs, "export <id> [--format markdown|html|json] [-o file]")
+			run = func(args []string, format export.Format) error {
+				if len(args) != 1 {
+					return fmt.Errorf("expected an item id")
+				}
+				id, err := strconv.Atoi(args[0])
+				if err != nil || id <= 0 {
+					return fmt.Errorf("invalid item id %q", args[0])
+				}
+				if !isFlagSet(fs, "format") {
+					format = export.Markdown
+					if len(*output) > 0 {
+						if format, err = export.FormatOf(*output); err != nil {
+							return err
+						}
+					}
+				}
+				return cmd.export(id, format, *output)
+			}
+		case "help":
+			flag.CommandLine.SetOutput(os.Stdout)
+			flag.Usage()
+			return 0
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+			flag.Usage()
+			return 2
+		}
+	}
+
+	rest, err := parseCommandFlags(fs, args[1:])
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	f, err := export.ParseFormat(*format)
+	if err == nil {
+		err = run(rest, f)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func commandUsage(fs *flag.FlagSet, synopsis string) func() {
+	return func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags] %s\n\nFlags:\n", os.Args[0], synopsis)
+		fs.PrintDefaults()
+	}
+}
+
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// Items come from the cache, or from the API (and are cached).
+func itemLoader(client hn.Client, c *cache.Cache, offline bool) thread.Load {
+	return func(ctx context.Context, id int) ([]byte, error) {
+		if bodyBytes, cached := c.Item(id, offline); cached {
+			return bodyBytes, nil
+		}
+		if offline {
+			// left out, like the items that don't exist
+			return nil, fmt.Errorf("item %d isn't cached: %w", id, hn.ErrNotFound)
+		}
+		bodyBytes, err := client.Item(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		_ = c.PutItem(id, bodyBytes)
+		return bodyBytes, nil
+	}
+}
+
+func (cmd *command) feed(feed hn.Feed, n int, format export.Format) error {
+	ctx := context.Background()
+	ids, cached := cmd.cache.Feed(string(feed), cmd.cfg.Cache.Offline)
+	if !cached {
+		if cmd.cfg.Cache.Offline {
+			return fmt.Errorf("the %s feed isn't cached", feed)
+		}
+		var err error
+		ids, err = cmd.client.Stories(ctx, feed)
+		if err != nil {
+			return err
+		}
+		_ = cmd.cache.PutFeed(string(feed), ids)
+	}
+	if n >= 0 && n < len(ids) {
+		ids = ids[:n]
+	}
+
+	stories, err := thread.Items(ctx, cmd.load, ids, cmd.cfg.Loading.MaxFetches)
+	if err != nil {
+		return err
+	}
+	return export.Stories(cmd.out, format, stories)
+}
+
+func (cmd *command) item(id int, depth int, format export.Format) error {
+	t, err := thread.Fetch(context.Background(), cmd.load, nil, id, depth, cmd.cfg.Loading.MaxFetches)
+	if err != nil {
+		return err
+	}
+	return export.Thread(cmd.out, format, t)
+}
+
+func (cmd *command) user(name string, format export.Format) error {
+	if cmd.cfg.Cache.Offline {
+		return fmt.Errorf("users aren't cached")
+	}
+	user, err := cmd.client.User(context.Background(), name)
+	if err != nil {
+		return err
+	}
+	return export.User(cmd.out, format, user)
+}
+
+func (cmd *command) export(id int, format export.Format, path string) error {
+	t, err := thread.Fetch(context.Background(), cmd.load, nil, id, -1, cmd.cfg.Loading.MaxFetches)
+	if err != nil {
+		return err
+	}
+	if len(path) == 0 {
+		return export.Thread(cmd.out, format, t)
+	}
+	return exportToFile(path, format, t)
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"hackerreader/algolia"
+	"hackerreader/files"
+	"hackerreader/hn"
+	"hackerreader/keys"
+	"hackerreader/scheduler"
+	"os"
+	"strings"
+	"time"
+)
+
+// The configuration file is TOML. Everything in it is optional: missing
+// settings keep their default value. Example (with the defaults):
+//
+//	feed = "top"      # feed shown on startup
+//	browser = ""      # command to open links with (default: system browser)
+//	mouse = true
+//	theme = ""        # dracula, light, mono or a .json/.toml theme file
+//	                  # (default: mono if NO_COLOR is set, dracula otherwise)
+//	killfile = ""     # rules to hide/dim posts (see the killfile package)
+//	                  # (default: $XDG_CONFIG_HOME/hackerreader/killfile)
+//	visits = ""       # stories read and comments seen (see the visits package)
+//	                  # (default: $XDG_STATE_HOME/hackerreader/visits.json)
+//	bookmarks = ""    # saved and hidden posts (see the bookmarks package)
+//	                  # (default: $XDG_DATA_HOME/hackerreader/bookmarks.json)
+//
+//	[layout]
+//	max_width = 135   # maximum width of the posts
+//
+//	[loading]
+//	backlog = 2       # replies of the hovered post loaded ahead of time
+//	prefetch = 10     # posts after the cursor loaded ahead of time
+//	max_fetches = 8   # posts fetched at the same time
+//
+//	[http]
+//	api_url = "https://hacker-news.firebaseio.com/v0"
+//	search_url = "https://hn.algolia.com/api/v1"
+//	timeout = "10s"
+//
+//	[cache]
+//	enabled = false
+//	dir = ""          # default: $XDG_CACHE_HOME/hackerreader
+//	offline = false
+//
+//	[live]
+//	enabled = true    # poll HN for the items that changed (see live.go)
+//	interval = "30s"  # time between two polls
+//
+//	[keys]            # binding name => keys (see the keys package)
+//	next_feed = ["tab", "]"]
+
+const (
+	minWidth        = 40
+	minTimeout      = 100 * time.Millisecond
+	minLiveInterval = 5 * time.Second
+)
+
+type Config struct {
+	Feed      string `toml:"feed"`
+	Browser   string `toml:"browser"`
+	Mouse     bool   `toml:"mouse"`
+	Theme     string `toml:"theme"`
+	Killfile  string `toml:"killfile"`
+	Visits    string `toml:"visits"`
+	Bookmarks string `toml:"bookmarks"`
+	Layout    struct {
+		MaxWidth int `toml:"max_width"`
+	} `toml:"layout"`
+	Loading struct {
+		Backlog    int `toml:"backlog"`
+		Prefetch   int `toml:"prefetch"`
+		MaxFetches int `toml:"max_fetches"`
+	} `toml:"loading"`
+	Http struct {
+		ApiUrl    string        `toml:"api_url"`
+		SearchUrl string        `toml:"search_url"`
+		Timeout   time.Duration `toml:"timeout"`
+	} `toml:"http"`
+	Cache struct {
+		Enabled bool   `toml:"enabled"`
+		Dir     string `toml:"dir"`
+		Offline bool   `toml:"offline"`
+	} `toml:"cache"`
+	Live struct {
+		Enabled  bool          `toml:"enabled"`
+		Interval time.Duration `toml:"interval"`
+	} `toml:"live"`
+	Keys map[string][]string `toml:"keys"`
+}
+
+func Default() Config {
+	var cfg Config
+	cfg.Feed = string(hn.TopFeed)
+	cfg.Mouse = true
+	cfg.Layout.MaxWidth = 135
+	cfg.Loading.Backlog = 2
+	cfg.Loading.Prefetch = 10
+	cfg.Loading.MaxFetches = scheduler.DefaultLimit
+	cfg.Http.ApiUrl = hn.DefaultBaseURL
+	cfg.Http.SearchUrl = algolia.DefaultBaseURL
+	cfg.Http.Timeout = hn.DefaultTimeout
+	cfg.Live.Enabled = true
+	cfg.Live.Interval = 30 * time.Second
+	return cfg
+}
+
+// DefaultPath is $XDG_CONFIG_HOME/hackerreader/config.toml (or the OS equivalent).
+func DefaultPath() (string, error) {
+	return files.ConfigPath("config.toml")
+}
+
+// Load reads the config file at path on top of the defaults. A missing file
+// is only an error if mustExist is true (i.e.: the user asked for that file).
+func Load(path string, mustExist bool) (Config, error) {
+	cfg := Default()
+	md, err := toml.DecodeFile(path, &cfg)
+	if errors.Is(err, os.ErrNotExist) {
+		if !mustExist {
+			return Default(), nil
+		}
+		return cfg, err
+	} else if err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		var keys []string
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return cfg, fmt.Errorf("%s: unknown settings: %s", path, strings.Join(keys, ", "))
+	}
+	if err = cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that every setting has a usable value.
+func (cfg *Config) Validate() error {
+	if _, err := hn.ParseFeed(cfg.Feed); err != nil {
+		return fmt.Errorf("feed: %w", err)
+	}
+	if cfg.Layout.MaxWidth < minWidth {
+		return fmt.Errorf("layout.max_width must be at least %d, got %d", minWidth, cfg.Layout.MaxWidth)
+	}
+	if cfg.Loading.Backlog < 0 {
+		return fmt.Errorf("loading.backlog can't be negative, got %d", cfg.Loading.Backlog)
+	}
+	if cfg.Loading.Prefetch < 0 {
+		return fmt.Errorf("loading.prefetch can't be negative, got %d", cfg.Loading.Prefetch)
+	}
+	if cfg.Loading.MaxFetches < 1 {
+		return fmt.Errorf("loading.max_fetches must be at least 1, got %d", cfg.Loading.MaxFetches)
+	}
+	if !strings.HasPrefix(cfg.Http.ApiUrl, "http://") && !strings.HasPrefix(cfg.Http.ApiUrl, "https://") {
+		return fmt.Errorf("http.api_url must be an http(s) URL, got %q", cfg.Http.ApiUrl)
+	}
+	if !strings.HasPrefix(cfg.Http.SearchUrl, "http://") && !strings.HasPrefix(cfg.Http.SearchUrl, "https://") {
+		return fmt.Errorf("http.search_url must be an http(s) URL, got %q", cfg.Http.SearchUrl)
+	}
+	if cfg.Http.Timeout < minTimeout {
+		return fmt.Errorf("http.timeout must be at least %s, got %s", minTimeout, cfg.Http.Timeout)
+	}
+	if cfg.Live.Interval < minLiveInterval {
+		return fmt.Errorf("live.interval must be at least %s, got %s", minLiveInterval, cfg.Live.Interval)
+	}
+	if _, err := keys.New(cfg.Keys); err != nil {
+		return fmt.Errorf("keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"hackerreader/hn"
+	"hackerreader/scheduler"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	maxRetries   = 5
+	retryBackoff = time.Second // doubles on each retry
+)
+
+// A global failure (e.g.: fetching a feed). It is shown on the status line
+// and retry (if not nil) runs the failed command again.
+type errMsg struct {
+	err   error
+	retry tea.Cmd
+}
+
+func (e errMsg) Error() string { return e.err.Error() }
+
+// A post that couldn't be loaded.
+type itemErrMsg struct {
+	stId   int
+	ticket int
+	err    error
+}
+
+type retryItemMsg struct {
+	stId int
+}
+
+// Marks the post as failed and schedules its next retry (if any is left).
+func (m *model) setItemErr(msg itemErrMsg) tea.Cmd {
+	st := m.getPost(msg.stId)
+	st.Err = msg.err
+	st.Failures++
+	if errors.Is(msg.err, hn.ErrNotFound) || st.Failures > maxRetries {
+		// retrying won't help/gave up => only manual retries
+		return nil
+	}
+
+	backoff := retryBackoff << (st.Failures - 1)
+	return tea.Tick(backoff, func(t time.Time) tea.Msg {
+		return retryItemMsg{stId: msg.stId}
+	})
+}
+
+// Queues the post for loading again (if it is still failed).
+func (m *model) retryItem(stId int) {
+	st := m.getPost(stId)
+	if st.Err != nil {
+		st.Err = nil
+		m.loader.Queue(stId, scheduler.Visible)
+	}
+}
+
+// Retries every failed post and the last global failure.
+func (m *model) retryAll() tea.Cmd {
+	for stId, st := range m.stories {
+		if st.Err != nil {
+			st.Failures = 0
+			m.retryItem(stId)
+		}
+	}
+
+	retry := m.retry
+	m.err = nil
+	m.retry = nil
+	return retry
+}
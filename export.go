@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hackerreader/export"
+	"hackerreader/thread"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Exporting a thread to a file (e.g.: to archive a discussion). The posts the
+// reader already loaded are used as they are, and the missing ones are
+// fetched (with the same limit as the reader's own fetches).
+
+type exportMsg struct {
+	path  string
+	posts int // how many were exported
+	err   error
+	retry tea.Cmd
+}
+
+func exportToFile(path string, format export.Format, t *thread.Thread) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = export.Thread(f, format, t); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Returns the post the export key exports: the selected post or, if none is
+// (e.g.: in a feed), the hovered one.
+func (m *model) exportTarget() (int, bool) {
+	candidates := []int{m.selected.Peek().(int)}
+	if stId, ok := m.hoveredId(); ok {
+		candidates = append(candidates, stId)
+	}
+	for _, stId := range candidates {
+		if m.getPost(stId).IsLoaded() {
+			return stId, true
+		}
+	}
+	return 0, false
+}
+
+// Copies the loaded posts of the thread (in HN's order): the export runs
+// outside of Update.
+func (m *model) knownPosts(stId int) thread.Known {
+	known := make(thread.Known)
+	var walk func(stId int)
+	walk = func(stId int) {
+		st, exists := m.stories[stId]
+		if !exists || !st.IsLoaded() {
+			return
+		}
+		kept := *st
+		kept.Kids = m.nativeKids(st)
+		known[stId] = kept
+		for _, kidId := range kept.Kids {
+			walk(kidId)
+		}
+	}
+	walk(stId)
+	return known
+}
+
+func (m *model) exportThread(stId int, path string, format export.Format) tea.Cmd {
+	known := m.knownPosts(stId)
+	load := itemLoader(m.client, m.cache, m.offline)
+	limit := m.cfg.Loading.MaxFetches
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		t, err := thread.Fetch(context.Background(), load, known, stId, -1, limit)
+		if err == nil {
+			err = exportToFile(path, format, t)
+		}
+		if err != nil {
+			return exportMsg{path: path, err: err, retry: cmd}
+		}
+		count := 0
+		t.Walk(func(*thread.Thread, int) {
+			count++
+		})
+		return exportMsg{path: path, posts: count}
+	}
+	return cmd
+}
+
+// The thread of the post, or the bookmarks for the root post (in the Saved
+// feed).
+func defaultExportPath(stId int) string {
+	if stId == rootStoryId {
+		return "hn-saved" + export.HTML.Extension()
+	}
+	return "hn-" + strconv.Itoa(stId) + export.Markdown.Extension()
+}
+
+func (m *model) openExport(stId int) {
+	m.exportId = stId
+	if stId == rootStoryId {
+		m.openPrompt(exportPrompt, "Export saved posts to: ",
+			defaultExportPath(stId)+" (a bookmarks file browsers can import)")
+		return
+	}
+	m.openPrompt(exportPrompt, "Export to: ",
+		defaultExportPath(stId)+" (the extension picks the format: .md, .html or .json)")
+}
+
+// Exports the thread to the file typed in the prompt.
+func (m *model) submitExport(value string) tea.Cmd {
+	path := strings.TrimSpace(value)
+	if len(path) == 0 {
+		path = defaultExportPath(m.exportId)
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	format, err := export.FormatOf(path)
+	if err != nil {
+		m.prompt.SetError(err)
+		return nil
+	}
+	if m.exportId == rootStoryId && format != export.HTML {
+		m.prompt.SetError(fmt.Errorf("the saved posts can only be exported to .html (not %s)", format))
+		return nil
+	}
+	m.prompt.Close()
+	m.notice = "Exporting to " + path + "..."
+	if m.exportId == rootStoryId {
+		return exportBookmarks(path, m.bookmarks.All())
+	}
+	return m.exportThread(m.exportId, path, format)
+}
+
+func (m *model) setExport(msg exportMsg) {
+	if msg.err != nil {
+		m.notice = ""
+		m.err = fmt.Errorf("couldn't export to %s: %w", msg.path, msg.err)
+		m.retry = msg.retry
+		return
+	}
+	m.notice = fmt.Sprintf("Exported %d posts to %s", msg.posts, msg.path)
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"fmt"
+	"hackerreader/bookmarks"
+	"hackerreader/posts"
+	"html"
+	"io"
+	"strings"
+)
+
+// Bookmarks are written in the Netscape bookmark file format, the one browsers
+// import (and export) bookmarks in. The saved posts go in a "Hacker News"
+// folder, with their tags.
+
+const excerptLen = 200 // runes of a comment's text kept in its description
+
+func Bookmarks(w io.Writer, marks []bookmarks.Bookmark) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n" +
+		"<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n" +
+		"<TITLE>Bookmarks</TITLE>\n<H1>Bookmarks</H1>\n<DL><p>\n" +
+		"    <DT><H3>Hacker News</H3>\n    <DL><p>\n")
+	for i := range marks {
+		b.WriteString(bookmarkHtml(&marks[i]))
+	}
+	b.WriteString("    </DL><p>\n</DL><p>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// The link to the post (to its URL for the stories that have one), and a
+// description with its info.
+func bookmarkHtml(mark *bookmarks.Bookmark) string {
+	st := posts.FromSnapshot(mark.Post, nil)
+	url := st.Permalink()
+	title := st.Title
+	desc := storyInfo(&st, timestamp(st.Time)) + " | " + st.Permalink()
+	if st.HasUrl() {
+		url = st.Url
+	}
+	if st.Storytype == "comment" {
+		title = "Comment by " + author(&st)
+		desc = excerpt(st.Text)
+	}
+	attrs := fmt.Sprintf(" ADD_DATE=\"%d\"", mark.Saved)
+	if len(mark.Tags) > 0 {
+		attrs += ` TAGS="` + html.EscapeString(strings.Join(mark.Tags, ",")) + `"`
+	}
+	return "        <DT><A HREF=\"" + html.EscapeString(url) + "\"" + attrs + ">" + html.EscapeString(title) + "</A>\n" +
+		"        <DD>" + html.EscapeString(desc) + "\n"
+}
+
+// The start of the text, on a single line.
+func excerpt(text string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= excerptLen {
+		return string(runes)
+	}
+	return string(runes[:excerptLen]) + "…"
+}
@@ -0,0 +1,335 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"hackerreader/hn"
+	"hackerreader/posts"
+	"hackerreader/thread"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Writing stories, threads and users as plain text, JSON, markdown or HTML
+// (e.g.: for the command line subcommands, or to archive a thread). Texts are
+// the markdown the posts are rendered from.
+
+type Format string
+
+const (
+	Text     Format = "text"
+	JSON     Format = "json"
+	Markdown Format = "markdown"
+	HTML     Format = "html"
+)
+
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "text", "txt":
+		return Text, nil
+	case "json":
+		return JSON, nil
+	case "markdown", "md":
+		return Markdown, nil
+	case "html", "htm":
+		return HTML, nil
+	}
+	return "", fmt.Errorf("unknown format %q (expected text, json, markdown or html)", name)
+}
+
+// FormatOf guesses the format of the file from its extension (e.g.: ".md").
+func FormatOf(path string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if len(ext) == 0 {
+		return "", fmt.Errorf("%q has no extension (expected .txt, .json, .md or .html)", path)
+	}
+	return ParseFormat(ext)
+}
+
+// Extension is the usual file extension for the format (e.g.: ".md").
+func (f Format) Extension() string {
+	switch f {
+	case JSON:
+		return ".json"
+	case Markdown:
+		return ".md"
+	case HTML:
+		return ".html"
+	default:
+		return ".txt"
+	}
+}
+
+// A post, as written in JSON (the API's fields, plus the permalink and the
+// replies that were fetched).
+type jsonPost struct {
+	Id          int        `json:"id"`
+	Type        string     `json:"type,omitempty"`
+	By          string     `json:"by,omitempty"`
+	Time        int        `json:"time,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Url         string     `json:"url,omitempty"`
+	Text        string     `json:"text,omitempty"` // markdown
+	Score       int        `json:"score,omitempty"`
+	Descendants int        `json:"descendants,omitempty"`
+	Parent      int        `json:"parent,omitempty"`
+	Kids        []int      `json:"kids,omitempty"`
+	Dead        bool       `json:"dead,omitempty"`
+	Deleted     bool       `json:"deleted,omitempty"`
+	Permalink   string     `json:"permalink"`
+	Replies     []jsonPost `json:"replies,omitempty"`
+}
+
+type jsonUser struct {
+	Id        string `json:"id"`
+	Created   int    `json:"created"`
+	Karma     int    `json:"karma"`
+	About     string `json:"about,omitempty"` // markdown
+	Submitted []int  `json:"submitted,omitempty"`
+	Permalink string `json:"permalink"`
+}
+
+func toJSON(st *posts.Post) jsonPost {
+	return jsonPost{
+		Id:          st.Id,
+		Type:        st.Storytype,
+		By:          st.By,
+		Time:        st.Time,
+		Title:       st.Title,
+		Url:         st.Url,
+		Text:        st.Text,
+		Score:       st.Score,
+		Descendants: st.Descendants,
+		Parent:      st.Parent,
+		Kids:        st.Kids,
+		Dead:        st.Dead,
+		Deleted:     st.Deleted,
+		Permalink:   st.Permalink(),
+	}
+}
+
+func threadToJSON(t *thread.Thread) jsonPost {
+	post := toJSON(&t.Post)
+	for _, reply := range t.Replies {
+		post.Replies = append(post.Replies, threadToJSON(reply))
+	}
+	return post
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Stories writes a list of stories (e.g.: a feed).
+func Stories(w io.Writer, format Format, stories []posts.Post) error {
+	if format == JSON {
+		list := make([]jsonPost, 0, len(stories))
+		for i := range stories {
+			list = append(list, toJSON(&stories[i]))
+		}
+		return writeJSON(w, list)
+	}
+	if format == HTML {
+		_, err := io.WriteString(w, storiesHtml(stories))
+		return err
+	}
+
+	var sb strings.Builder
+	for i := range stories {
+		st := &stories[i]
+		if format == Markdown {
+			sb.WriteString(storyMarkdown(st, i+1))
+		} else {
+			sb.WriteString(storyText(st, i+1))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Thread writes a post and its replies.
+func Thread(w io.Writer, format Format, t *thread.Thread) error {
+	switch format {
+	case JSON:
+		return writeJSON(w, threadToJSON(t))
+	case HTML:
+		_, err := io.WriteString(w, threadHtml(t))
+		return err
+	}
+
+	var sb strings.Builder
+	t.Walk(func(t *thread.Thread, depth int) {
+		if format == Markdown {
+			sb.WriteString(postMarkdown(&t.Post, depth))
+		} else {
+			sb.WriteString(postText(&t.Post, depth))
+		}
+	})
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// User writes a user's profile.
+func User(w io.Writer, format Format, user hn.User) error {
+	profile := posts.New(nil)
+	profile.SetUser(user)
+	permalink := posts.UserUrl + user.Id
+
+	switch format {
+	case JSON:
+		return writeJSON(w, jsonUser{
+			Id:        user.Id,
+			Created:   user.Created,
+			Karma:     user.Karma,
+			About:     profile.Text,
+			Submitted: user.Submitted,
+			Permalink: permalink,
+		})
+	case HTML:
+		_, err := io.WriteString(w, userHtml(user, &profile))
+		return err
+	case Markdown:
+		md := fmt.Sprintf("# [%s](%s)\n\n%d karma | joined %s | %d submissions\n\n",
+			escapeMd(user.Id), permalink, user.Karma, timestamp(user.Created), len(user.Submitted))
+		if profile.HasText() {
+			md += strings.TrimSpace(profile.Text) + "\n"
+		}
+		_, err := io.WriteString(w, md)
+		return err
+	default:
+		txt := fmt.Sprintf("%s\n%d karma | joined %s | %d submissions\n%s\n",
+			user.Id, user.Karma, profile.TimeStr, len(user.Submitted), permalink)
+		if profile.HasText() {
+			txt += "\n" + strings.TrimSpace(profile.Text) + "\n"
+		}
+		_, err := io.WriteString(w, txt)
+		return err
+	}
+}
+
+// e.g.: "42 points by dang 3 hours ago | 13 comments"
+func storyInfo(st *posts.Post, when string) string {
+	info := fmt.Sprintf("%d points by %s %s", st.Score, st.By, when)
+	if st.Storytype != "job" {
+		info += fmt.Sprintf(" | %d comments", st.Descendants)
+	}
+	return info
+}
+
+func storyText(st *posts.Post, rank int) string {
+	txt := fmt.Sprintf("%3d. %s", rank, st.Title)
+	if len(st.Domain) > 0 {
+		txt += " (" + st.Domain + ")"
+	}
+	txt += "\n     " + storyInfo(st, st.TimeStr) + "\n"
+	if st.HasUrl() {
+		txt += "     " + st.Url + "\n"
+	}
+	return txt + "     " + st.Permalink() + "\n"
+}
+
+func storyMarkdown(st *posts.Post, rank int) string {
+	title := escapeMd(st.Title)
+	if st.HasUrl() {
+		title = "[" + title + "](" + st.Url + ")"
+	}
+	if len(st.Domain) > 0 {
+		title += " (" + st.Domain + ")"
+	}
+	return fmt.Sprintf("%d. %s  \n   %s | [discuss](%s)\n",
+		rank, title, storyInfo(st, timestamp(st.Time)), st.Permalink())
+}
+
+// The post (depth levels of replies down the thread), indented by its depth.
+func postText(st *posts.Post, depth int) string {
+	indent := strings.Repeat("  ", depth)
+	var lines []string
+	if depth == 0 && len(st.Title) > 0 {
+		title := st.Title
+		if len(st.Domain) > 0 {
+			title += " (" + st.Domain + ")"
+		}
+		lines = append(lines, title, storyInfo(st, st.TimeStr))
+		if st.HasUrl() {
+			lines = append(lines, st.Url)
+		}
+		lines = append(lines, st.Permalink())
+		if st.HasText() {
+			lines = append(lines, "")
+		}
+	} else {
+		lines = append(lines, fmt.Sprintf("%s %s [%d]", author(st), st.TimeStr, st.Id))
+	}
+	if st.HasText() {
+		lines = append(lines, strings.Split(strings.TrimSpace(st.Text), "\n")...)
+	}
+	txt := ""
+	for _, line := range lines {
+		if len(line) > 0 {
+			txt += indent + line
+		}
+		txt += "\n"
+	}
+	return txt + "\n"
+}
+
+// The root is a heading (unless it's a comment), and the replies are quoted
+// once per level of depth.
+func postMarkdown(st *posts.Post, depth int) string {
+	var lines []string
+	if depth == 0 && len(st.Title) > 0 {
+		title := escapeMd(st.Title)
+		if st.HasUrl() {
+			title = "[" + title + "](" + st.Url + ")"
+		}
+		lines = append(lines, "# "+title, "",
+			fmt.Sprintf("%s | [permalink](%s)", storyInfo(st, timestamp(st.Time)), st.Permalink()))
+	} else {
+		lines = append(lines, fmt.Sprintf("**%s** · [%s](%s)",
+			escapeMd(author(st)), timestamp(st.Time), st.Permalink()))
+	}
+	if st.HasText() {
+		lines = append(lines, "")
+		lines = append(lines, strings.Split(strings.TrimSpace(st.Text), "\n")...)
+	}
+
+	md := ""
+	if depth > 0 {
+		// ends the previous post, but not the quotes it shares with this one
+		md = strings.TrimSpace(strings.Repeat("> ", depth-1)) + "\n"
+	}
+	quote := strings.Repeat("> ", depth)
+	for _, line := range lines {
+		if len(line) == 0 {
+			md += strings.TrimSpace(quote) + "\n"
+		} else {
+			md += quote + line + "\n"
+		}
+	}
+	return md
+}
+
+func author(st *posts.Post) string {
+	switch {
+	case st.Deleted:
+		return "[deleted]"
+	case st.Dead:
+		return st.By + " [dead]"
+	}
+	return st.By
+}
+
+// Absolute times: exports are read later on (when "3 hours ago" is wrong).
+func timestamp(unix int) string {
+	return time.Unix(int64(unix), 0).UTC().Format("2006-01-02 15:04 UTC")
+}
+
+var mdEscaper = strings.NewReplacer("[", "\\[", "]", "\\]", "*", "\\*", "_", "\\_", "`", "\\`")
+
+func escapeMd(s string) string {
+	return mdEscaper.Replace(s)
+}
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"hackerreader/hn"
+	"hackerreader/posts"
+	"hackerreader/thread"
+	"html"
+	"time"
+
+	"github.com/yuin/goldmark"
+)
+
+// Standalone HTML pages (no external stylesheet or script). The texts go from
+// markdown back to HTML, and the replies of a thread are nested <details>, so
+// they can be collapsed in the browser.
+
+const pageStyle = `
+body { max-width: 50em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; color: #222; }
+a { color: #b35900; }
+.meta, summary { color: #666; font-size: 0.9em; }
+summary { cursor: pointer; }
+details { margin: 0.5em 0 0.5em 0.25em; padding-left: 1em; border-left: 2px solid #ddd; }
+details > details { margin-left: 0; }
+pre { overflow-x: auto; background: #f4f4f4; padding: 0.5em; }
+ol li { margin-bottom: 0.5em; }
+`
+
+func page(title string, body string) string {
+	return "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n" +
+		"<title>" + html.EscapeString(title) + "</title>\n" +
+		"<style>" + pageStyle + "</style>\n</head>\n<body>\n" + body + "</body>\n</html>\n"
+}
+
+func mdToHtml(md string) string {
+	var buf bytes.Buffer
+	if err := goldmark.Convert([]byte(md), &buf); err != nil {
+		return "<pre>" + html.EscapeString(md) + "</pre>\n"
+	}
+	return buf.String()
+}
+
+func linkHtml(url string, text string) string {
+	return `<a href="` + html.EscapeString(url) + `">` + html.EscapeString(text) + `</a>`
+}
+
+func timeHtml(unix int) string {
+	t := time.Unix(int64(unix), 0).UTC()
+	return `<time datetime="` + t.Format(time.RFC3339) + `">` + timestamp(unix) + `</time>`
+}
+
+func authorHtml(st *posts.Post) string {
+	switch {
+	case st.Deleted:
+		return html.EscapeString(author(st))
+	case st.Dead:
+		return linkHtml(posts.UserUrl+st.By, st.By) + " [dead]"
+	}
+	return linkHtml(posts.UserUrl+st.By, st.By)
+}
+
+func titleHtml(st *posts.Post) string {
+	title := html.EscapeString(st.Title)
+	if st.HasUrl() {
+		title = linkHtml(st.Url, st.Title)
+	}
+	if len(st.Domain) > 0 {
+		title += ` <span class="meta">(` + html.EscapeString(st.Domain) + `)</span>`
+	}
+	return title
+}
+
+func storyInfoHtml(st *posts.Post) string {
+	info := fmt.Sprintf("%d points by %s %s", st.Score, authorHtml(st), `<a href="`+st.Permalink()+`">`+timeHtml(st.Time)+`</a>`)
+	if st.Storytype != "job" {
+		info += fmt.Sprintf(" | %d comments", st.Descendants)
+	}
+	return info
+}
+
+func storiesHtml(stories []posts.Post) string {
+	body := "<ol>\n"
+	for i := range stories {
+		st := &stories[i]
+		body += "<li>" + titleHtml(st) + "<br>\n" +
+			`<span class="meta">` + storyInfoHtml(st) + "</span></li>\n"
+	}
+	return page("Hacker News", body+"</ol>\n")
+}
+
+func threadHtml(t *thread.Thread) string {
+	var body, title string
+	if len(t.Title) > 0 {
+		title = t.Title
+		body = "<article>\n<h1>" + titleHtml(&t.Post) + "</h1>\n" +
+			`<p class="meta">` + storyInfoHtml(&t.Post) + "</p>\n"
+		if t.HasText() {
+			body += mdToHtml(t.Text)
+		}
+		body += "</article>\n"
+		for _, reply := range t.Replies {
+			body += replyHtml(reply)
+		}
+	} else {
+		title = "Comment by " + author(&t.Post)
+		body = replyHtml(t)
+	}
+	return page(title, body)
+}
+
+// The post and its replies, collapsible (open by default).
+func replyHtml(t *thread.Thread) string {
+	summary := authorHtml(&t.Post) + ` · <a href="` + t.Permalink() + `">` + timeHtml(t.Time) + `</a>`
+	switch len(t.Replies) {
+	case 0:
+	case 1:
+		summary += " · 1 reply"
+	default:
+		summary += fmt.Sprintf(" · %d replies", len(t.Replies))
+	}
+	ret := fmt.Sprintf("<details open id=\"item-%d\">\n<summary>%s</summary>\n", t.Id, summary)
+	if t.HasText() {
+		ret += mdToHtml(t.Text)
+	}
+	for _, reply := range t.Replies {
+		ret += replyHtml(reply)
+	}
+	return ret + "</details>\n"
+}
+
+func userHtml(user hn.User, profile *posts.Post) string {
+	body := "<h1>" + linkHtml(posts.UserUrl+user.Id, user.Id) + "</h1>\n" +
+		fmt.Sprintf(`<p class="meta">%d karma | joined %s | %d submissions</p>`+"\n",
+			user.Karma, timeHtml(user.Created), len(user.Submitted))
+	if profile.HasText() {
+		body += mdToHtml(profile.Text)
+	}
+	return page(user.Id, body)
+}
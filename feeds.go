@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hackerreader/hn"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type feedMsg struct {
+	feed    hn.Feed
+	stories []int
+}
+
+func (m *model) fetchFeed(feed hn.Feed) tea.Cmd {
+	return func() tea.Msg {
+		if stories, cached := m.cache.Feed(string(feed), m.offline); cached || m.offline {
+			return feedMsg{feed: feed, stories: stories}
+		}
+		stories, err := m.client.Stories(context.Background(), feed)
+		if err != nil {
+			return errMsg{err: err, retry: m.fetchFeed(feed)}
+		}
+		_ = m.cache.PutFeed(string(feed), stories)
+		return feedMsg{feed: feed, stories: stories}
+	}
+}
+
+// Shows the given feed on the root story. Each feed remembers its own cursor.
+func (m *model) switchFeed(feed hn.Feed) tea.Cmd {
+	// go back to the root list (its cursor is the bottom of the stack)
+	for m.selected.Len() > 1 {
+		m.cursor = m.prevCursor.Pop().(int)
+		m.selected.Pop()
+	}
+	m.inFocus = -1
+	m.treeMode = false
+	m.clearFind()
+
+	m.feedCursor[m.feed] = m.cursor
+	m.feed = feed
+	m.cursor = m.feedCursor[feed]
+
+	if feed == savedFeed {
+		// not from the API => always up to date
+		m.feeds[feed] = m.savedStories()
+		if len(m.feeds[feed]) == 0 {
+			m.notice = fmt.Sprintf("No saved posts yet (%s to save one)", m.keys.Bookmark.Help().Key)
+		}
+	}
+	stories, fetched := m.feeds[feed]
+	if !fetched {
+		// show loading screen until the feed arrives
+		m.loaded = false
+		m.getPost(rootStoryId).Kids = nil
+		m.cancelLoads()
+		return m.fetchFeed(feed)
+	}
+	m.loaded = true
+	m.setRootStories(stories)
+	m.cancelLoads()
+	return nil
+}
+
+// Moves n feeds forward (or backwards if negative), wrapping around.
+func (m *model) cycleFeed(n int) tea.Cmd {
+	i := 0
+	for j, feed := range feedOrder {
+		if feed == m.feed {
+			i = j
+			break
+		}
+	}
+	i = (i + n + len(feedOrder)) % len(feedOrder)
+	return m.switchFeed(feedOrder[i])
+}
+
+func (m *model) setRootStories(stories []int) {
+	rootStory := m.getPost(rootStoryId)
+	rootStory.Kids = stories
+	rootStory.Descendants = len(stories)
+	m.sortKids(rootStory) // same order as when we left it
+	if m.selected.Len() == 1 {
+		m.moveCursor(m.cursor) // keep the cursor in bounds
+	}
+}
@@ -0,0 +1,80 @@
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Where HackerReader keeps its files (following the XDG base directories),
+// and how they are written.
+
+const appDir = "hackerreader"
+
+// ConfigPath is $XDG_CONFIG_HOME/hackerreader/name (or the OS equivalent).
+func ConfigPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appDir, name), nil
+}
+
+// CachePath is $XDG_CACHE_HOME/hackerreader/name (or the OS equivalent).
+func CachePath(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appDir, name), nil
+}
+
+// DataPath is $XDG_DATA_HOME/hackerreader/name
+// (~/.local/share/hackerreader/name if it isn't set).
+func DataPath(name string) (string, error) {
+	return xdgPath("XDG_DATA_HOME", filepath.Join(".local", "share"), name)
+}
+
+// StatePath is $XDG_STATE_HOME/hackerreader/name
+// (~/.local/state/hackerreader/name if it isn't set).
+func StatePath(name string) (string, error) {
+	return xdgPath("XDG_STATE_HOME", filepath.Join(".local", "state"), name)
+}
+
+// The Go standard library only knows the config and cache directories.
+func xdgPath(env string, homeDefault string, name string) (string, error) {
+	dir := os.Getenv(env)
+	if len(dir) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, homeDefault)
+	}
+	return filepath.Join(dir, appDir, name), nil
+}
+
+// WriteAtomic writes the file through a temporary file renamed over it: a
+// crash never leaves half a file, and readers never see one. Its directory
+// is created if needed.
+func WriteAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
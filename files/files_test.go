@@ -0,0 +1,62 @@
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "file.json")
+	for _, data := range []string{"first", "second"} {
+		if err := WriteAtomic(path, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ioutil.ReadFile(path); err != nil || string(got) != data {
+			t.Errorf("read %q (%v), want %q", got, err, data)
+		}
+	}
+	assertOnly(t, filepath.Join(dir, "sub"), "file.json")
+}
+
+func TestWriteAtomicRenameFails(t *testing.T) {
+	dir := t.TempDir()
+	// a directory can't be replaced by a file
+	path := filepath.Join(dir, "taken")
+	if err := os.MkdirAll(filepath.Join(path, "inside"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAtomic(path, []byte("data")); err == nil {
+		t.Fatal("WriteAtomic() over a directory succeeded")
+	}
+	assertOnly(t, dir, "taken") // no temporary file left behind
+}
+
+func assertOnly(t *testing.T, dir string, name string) {
+	t.Helper()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != name {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("%s has %v, want only %s", dir, names, name)
+	}
+}
+
+func TestXdgPaths(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+	if path, err := StatePath("visits.json"); err != nil || path != "/state/hackerreader/visits.json" {
+		t.Errorf("StatePath() = %q, %v", path, err)
+	}
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("HOME", "/home/someone")
+	if path, err := DataPath("bookmarks.json"); err != nil || path != "/home/someone/.local/share/hackerreader/bookmarks.json" {
+		t.Errorf("DataPath() = %q, %v", path, err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"hackerreader/killfile"
+	"hackerreader/posts"
+	"hackerreader/style"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Sets what the killfile does with the post (nothing while filtered posts
+// are being shown).
+func (m *model) applyFilter(st *posts.Post) {
+	if m.showFiltered {
+		st.Filter = posts.Unfiltered
+	} else {
+		st.Filter = m.killfile.Check(st)
+	}
+}
+
+// Applies the killfile to every post again (after the rules change).
+func (m *model) refilter() {
+	for _, st := range m.stories {
+		m.applyFilter(st)
+	}
+}
+
+func (m *model) toggleShowFiltered() {
+	m.showFiltered = !m.showFiltered
+	m.refilter()
+}
+
+func (m *model) openKillfile() {
+	m.killfileOpen = true
+	m.killfileCursor = 0
+}
+
+func (m *model) killfileKeyHandler(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyCtrlC:
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Back), key.Matches(msg, m.keys.Killfile), key.Matches(msg, m.keys.Quit):
+		m.killfileOpen = false
+	case key.Matches(msg, m.keys.Down):
+		m.killfileCursor = min(m.killfileCursor+1, max(0, len(m.killfile.Rules)-1))
+	case key.Matches(msg, m.keys.Up):
+		m.killfileCursor = max(m.killfileCursor-1, 0)
+	case key.Matches(msg, m.keys.KillfileAdd):
+		m.openPrompt(rulePrompt, "Rule: ", killfile.RuleSyntax)
+	case key.Matches(msg, m.keys.KillfileRemove):
+		if err := m.killfile.Remove(m.killfileCursor); err != nil {
+			m.err = fmt.Errorf("couldn't save the killfile: %w", err)
+		}
+		m.killfileCursor = max(0, min(m.killfileCursor, len(m.killfile.Rules)-1))
+		m.refilter()
+	}
+	return m, nil
+}
+
+// Adds the rule typed in the prompt to the killfile.
+func (m *model) submitRule(value string) {
+	rule, err := killfile.ParseRule(value)
+	if err != nil {
+		m.prompt.SetError(err)
+		return
+	}
+	if err = m.killfile.Add(rule); err != nil {
+		m.prompt.SetError(fmt.Errorf("couldn't save the killfile: %w", err))
+		return
+	}
+	m.prompt.Close()
+	m.killfileCursor = len(m.killfile.Rules) - 1
+	m.refilter()
+}
+
+func (m *model) killfileView() string {
+	title := style.PrimaryStyle.Copy().Bold(true)
+	rows := []string{
+		title.Render("Killfile") + style.SecondaryStyle.Render(" "+m.killfile.Path()),
+		"",
+	}
+	if len(m.killfile.Rules) == 0 {
+		rows = append(rows, style.SecondaryStyle.Render("No rules yet"))
+	}
+	for i, rule := range m.killfile.Rules {
+		cursor := " "
+		if i == m.killfileCursor {
+			cursor = style.Checkmark(">")
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top,
+			cursor, " ",
+			style.PrimaryStyle.Copy().Bold(i == m.killfileCursor).Render(rule.String()),
+		))
+	}
+
+	h := help.New()
+	h.Width = m.cappedW - 2
+	h.Styles.FullKey = style.PrimaryStyle.Copy().Bold(true)
+	h.Styles.FullDesc = style.SecondaryStyle
+	rows = append(rows, "", h.FullHelpView(m.keys.KillfileHelp()))
+	if m.showFiltered {
+		rows = append(rows, "", style.SecondaryStyle.Render(fmt.Sprintf(
+			"Filtered posts are being shown (%s to hide them)", m.keys.ShowFiltered.Help().Key)))
+	}
+	return style.MainItem.
+		Width(m.cappedW - 2).
+		Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
+}
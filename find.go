@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"hackerreader/posts"
+	"hackerreader/style"
+)
+
+const findPlaceholder = "text, title or author"
+
+// State of the search inside the current thread (the `/` prompt).
+type finder struct {
+	query   string
+	storyId int   // the thread being searched
+	matches []int // ids of the matching posts, in thread order
+	current int   // index (in matches) of the match we're on (-1 => none yet)
+	depth   int   // len of the selected stack when the search started
+}
+
+// Returns the story whose thread is being read: the top-most real post that
+// is selected, or the hovered one when none is (e.g.: in a list of stories).
+func (m *model) threadStoryId() (int, bool) {
+	stId := m.selected.Peek().(int)
+	if stId == rootStoryId || m.getPost(stId).IsPseudo() {
+		var ok bool
+		if stId, ok = m.hoveredId(); !ok {
+			return 0, false
+		}
+	}
+	// climb up to the story (only through posts we already have)
+	for {
+		st, exists := m.stories[stId]
+		if !exists || st.Parent == 0 {
+			break
+		}
+		if parent, exists := m.stories[st.Parent]; !exists || !parent.IsLoaded() {
+			break
+		}
+		stId = st.Parent
+	}
+	return stId, true
+}
+
+// Returns the ids of everything loaded under the story (itself included)
+// that matches the query, in thread order. Nothing is loaded by this.
+func (m *model) findMatches(storyId int, query string) []int {
+	var matches []int
+	var walk func(stId int)
+	walk = func(stId int) {
+		st, exists := m.stories[stId]
+		if !exists || !st.IsLoaded() {
+			return
+		}
+		if st.Matches(query) {
+			matches = append(matches, stId)
+		}
+		for _, kidId := range st.Kids {
+			walk(kidId)
+		}
+	}
+	walk(storyId)
+	return matches
+}
+
+// Updates the matches of the current search (posts might have been loaded
+// since they were last found).
+func (m *model) refreshMatches() {
+	var currentId int
+	if m.find.current >= 0 && m.find.current < len(m.find.matches) {
+		currentId = m.find.matches[m.find.current]
+	}
+	for _, stId := range m.find.matches {
+		m.stories[stId].Match = ""
+	}
+
+	m.find.matches = m.findMatches(m.find.storyId, m.find.query)
+	m.find.current = -1
+	for i, stId := range m.find.matches {
+		m.stories[stId].Match = m.find.query
+		if stId == currentId {
+			m.find.current = i
+		}
+	}
+}
+
+// Highlights the post if it's part of the current search (for posts loaded
+// after the search was made).
+func (m *model) markMatch(st *posts.Post) {
+	if len(m.find.query) == 0 || !st.Matches(m.find.query) {
+		return
+	}
+	// only posts in the searched thread
+	for ancestor := st; ancestor.Id != m.find.storyId; {
+		parent, exists := m.stories[ancestor.Parent]
+		if ancestor.Parent == 0 || !exists {
+			return
+		}
+		ancestor = parent
+	}
+	st.Match = m.find.query
+}
+
+func (m *model) clearFind() {
+	for _, stId := range m.find.matches {
+		m.stories[stId].Match = ""
+	}
+	m.find = finder{current: -1}
+}
+
+// Searches the current thread for the text typed in the prompt and jumps to
+// the first match.
+func (m *model) submitFind(value string) {
+	m.prompt.Close()
+	m.clearFind()
+	if len(value) == 0 {
+		return
+	}
+	storyId, ok := m.threadStoryId()
+	if !ok {
+		return
+	}
+	m.find.query = value
+	m.find.storyId = storyId
+	m.find.depth = m.selected.Len()
+	m.refreshMatches()
+	m.jumpToMatch(0)
+}
+
+// Moves n matches forward (or backwards if negative), wrapping around.
+func (m *model) nextMatch(n int) {
+	if len(m.find.query) == 0 {
+		return
+	}
+	m.refreshMatches()
+	if len(m.find.matches) == 0 {
+		return
+	}
+	i := m.find.current + n
+	if m.find.current < 0 && n < 0 {
+		i = -1 // lost our place => go to the last one
+	}
+	i %= len(m.find.matches)
+	if i < 0 {
+		i += len(m.find.matches)
+	}
+	m.jumpToMatch(i)
+}
+
+// Shows the i-th match.
+func (m *model) jumpToMatch(i int) {
+	if i >= len(m.find.matches) {
+		return
+	}
+	m.find.current = i
+	m.showPost(m.find.storyId, m.find.matches[i], &m.find.depth)
+}
+
+// Shows the post of the story's thread. In tree mode, the cursor is moved to
+// it. Otherwise, the post's parent is selected, on top of the selection at
+// *depth (e.g.: where a search started), so going back returns there.
+func (m *model) showPost(storyId int, stId int, depth *int) {
+	st := m.stories[stId]
+	m.inFocus = -1
+	m.collapseMain = false
+
+	if m.treeMode && m.selected.Peek().(int) == storyId {
+		// the whole thread is on screen => make sure the post isn't collapsed
+		for ancestor := st; ancestor.Id != storyId; ancestor = m.stories[ancestor.Parent] {
+			delete(m.collapsed, ancestor.Parent)
+		}
+		if stId != storyId {
+			m.treeCursor = stId
+		}
+		return
+	}
+
+	// back to where we started
+	if m.selected.Len() < *depth {
+		*depth = m.selected.Len()
+	}
+	for m.selected.Len() > *depth {
+		m.cursor = m.prevCursor.Pop().(int)
+		m.selected.Pop()
+	}
+
+	m.treeMode = false
+	m.prevCursor.Push(m.cursor)
+	if stId == storyId {
+		// the story itself => show it as the main item
+		m.selected.Push(stId)
+		m.cursor = 0
+	} else {
+		parentId := st.Parent
+		m.selected.Push(parentId)
+		m.cursor = indexOf(m.stories[parentId].Kids, stId)
+	}
+	m.cancelLoads()
+	m.moveCursor(m.cursor)
+}
+
+// Returns the status line of the current search.
+func (m *model) findView() string {
+	var status string
+	if len(m.find.matches) == 0 {
+		status = "no matches"
+	} else if m.find.current < 0 {
+		status = fmt.Sprintf("%d matches", len(m.find.matches))
+	} else {
+		status = fmt.Sprintf("match %d of %d", m.find.current+1, len(m.find.matches))
+	}
+	return style.SecondaryStyle.Copy().MaxWidth(m.w).Render(
+		fmt.Sprintf("/%s: %s (%s/%s to move)",
+			m.find.query, status, m.keys.FindNext.Help().Key, m.keys.FindPrev.Help().Key))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hackerreader/config"
+	"os"
+)
+
+// Reads the config file and the command line flags (flags win over the file)
+func parseFlags() (config.Config, error) {
+	defaults := config.Default()
+	configPath := flag.String("config", "", "config file (default $XDG_CONFIG_HOME/hackerreader/config.toml)")
+	apiUrl := flag.String("api", defaults.Http.ApiUrl, "base URL of the HackerNews API")
+	searchApiUrl := flag.String("search-api", defaults.Http.SearchUrl, "base URL of the HackerNews search (Algolia) API")
+	feedName := flag.String("feed", defaults.Feed, "feed to start on: top, new, best, ask, show or job")
+	useCache := flag.Bool("cache", defaults.Cache.Enabled, "cache the fetched items on disk")
+	cacheDir := flag.String("cache-dir", defaults.Cache.Dir, "cache directory (default $XDG_CACHE_HOME/hackerreader)")
+	offline := flag.Bool("offline", defaults.Cache.Offline, "browse only what is cached (implies --cache)")
+	fetchLimit := flag.Int("max-fetches", defaults.Loading.MaxFetches, "maximum number of posts fetched at the same time")
+	live := flag.Bool("live", defaults.Live.Enabled, "poll HN for updates of the loaded posts")
+	liveInterval := flag.Duration("live-interval", defaults.Live.Interval, "time between two polls for updates")
+	mouse := flag.Bool("mouse", defaults.Mouse, "enable mouse support")
+	killfilePath := flag.String("killfile", defaults.Killfile, "killfile: rules to hide/dim posts (default $XDG_CONFIG_HOME/hackerreader/killfile)")
+	visitsPath := flag.String("visits", defaults.Visits, "file where the stories read are kept (default $XDG_STATE_HOME/hackerreader/visits.json)")
+	bookmarksPath := flag.String("bookmarks", defaults.Bookmarks, "file where the saved and hidden posts are kept (default $XDG_DATA_HOME/hackerreader/bookmarks.json)")
+	theme := flag.String("theme", defaults.Theme, "theme: dracula, light, mono or a .json/.toml theme file")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: %s [flags] [command]\n\n%s\nFlags:\n", os.Args[0], commandsUsage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var cfg config.Config
+	var err error
+	if len(*configPath) > 0 {
+		cfg, err = config.Load(*configPath, true)
+	} else {
+		var path string
+		path, err = config.DefaultPath()
+		if err == nil {
+			cfg, err = config.Load(path, false)
+		}
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	// only the flags that were given override the config file
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "api":
+			cfg.Http.ApiUrl = *apiUrl
+		case "search-api":
+			cfg.Http.SearchUrl = *searchApiUrl
+		case "feed":
+			cfg.Feed = *feedName
+		case "cache":
+			cfg.Cache.Enabled = *useCache
+		case "cache-dir":
+			cfg.Cache.Dir = *cacheDir
+		case "offline":
+			cfg.Cache.Offline = *offline
+		case "max-fetches":
+			cfg.Loading.MaxFetches = *fetchLimit
+		case "live":
+			cfg.Live.Enabled = *live
+		case "live-interval":
+			cfg.Live.Interval = *liveInterval
+		case "mouse":
+			cfg.Mouse = *mouse
+		case "theme":
+			cfg.Theme = *theme
+		case "killfile":
+			cfg.Killfile = *killfilePath
+		case "visits":
+			cfg.Visits = *visitsPath
+		case "bookmarks":
+			cfg.Bookmarks = *bookmarksPath
+		}
+	})
+	if err = cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid flag: %w", err)
+	}
+	return cfg, nil
+}
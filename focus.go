@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"hackerreader/style"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Focus mode shows a single post (the whole screen for it) in a scrollable
+// viewport.
+
+// How far the viewport is scrolled, in %. The viewport's own ScrollPercent is
+// off by one line (NaN when there's a single line to scroll).
+func scrollPercent(vp viewport.Model) float64 {
+	if vp.AtBottom() {
+		return 100
+	}
+	if vp.AtTop() {
+		return 0
+	}
+	return vp.ScrollPercent() * 100
+}
+
+func (m *model) openFocus(stId int) {
+	m.inFocus = stId
+	m.focusView = viewport.Model{}
+	m.setFocusContent()
+}
+
+func (m *model) closeFocus() {
+	m.inFocus = -1
+}
+
+// Renders the post in focus again (e.g.: it loaded or the terminal was
+// resized). The scroll position is kept (as far as the new content allows).
+func (m *model) setFocusContent() {
+	if m.inFocus <= 0 {
+		return
+	}
+	m.focusView.Width = m.cappedW
+	m.focusView.Height = m.viewportHeight()
+	m.focusView.SetContent(m.getPost(m.inFocus).View(false, true, m.cappedW, m.stories))
+}
+
+// Returns the list the post in focus is in (the selected post's kids or, in
+// tree mode, its parent's) and its index in it.
+func (m *model) focusSiblings() ([]int, int) {
+	if kids := m.getPost(m.selected.Peek().(int)).Kids; indexOf(kids, m.inFocus) >= 0 {
+		return kids, indexOf(kids, m.inFocus)
+	}
+	parent, exists := m.stories[m.getPost(m.inFocus).Parent]
+	if !exists {
+		return nil, -1
+	}
+	return parent.Kids, indexOf(parent.Kids, m.inFocus)
+}
+
+// Focuses the n-th sibling after the post in focus (before it if negative).
+// The cursor follows, so leaving focus mode lands on the last focused post.
+func (m *model) focusSibling(n int) {
+	siblings, i := m.focusSiblings()
+	if i < 0 {
+		return
+	}
+	i = max(0, min(i+n, len(siblings)-1))
+	if siblings[i] == m.inFocus {
+		return
+	}
+	m.openFocus(siblings[i])
+	if m.treeMode {
+		m.treeCursor = siblings[i]
+	} else {
+		m.moveCursor(i)
+	}
+}
+
+func (m *model) focusKeyHandler(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	vp := &m.focusView
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Help):
+		m.showHelp = true
+	case key.Matches(msg, m.keys.Focus), key.Matches(msg, m.keys.Back):
+		m.closeFocus()
+	case key.Matches(msg, m.keys.Links):
+		m.openLinks()
+		return m, m.fetchPreview()
+	case key.Matches(msg, m.keys.Down):
+		vp.LineDown(1)
+	case key.Matches(msg, m.keys.Up):
+		vp.LineUp(1)
+	case key.Matches(msg, m.keys.PageDown):
+		vp.ViewDown()
+	case key.Matches(msg, m.keys.PageUp):
+		vp.ViewUp()
+	case key.Matches(msg, m.keys.HalfPageDown):
+		vp.HalfViewDown()
+	case key.Matches(msg, m.keys.HalfPageUp):
+		vp.HalfViewUp()
+	case key.Matches(msg, m.keys.Top):
+		vp.GotoTop()
+	case key.Matches(msg, m.keys.Bottom):
+		vp.GotoBottom()
+	case key.Matches(msg, m.keys.FocusNext):
+		m.focusSibling(1)
+	case key.Matches(msg, m.keys.FocusPrev):
+		m.focusSibling(-1)
+	}
+	return m, nil
+}
+
+func (m *model) focusModeView() string {
+	body := lipgloss.NewStyle().
+		Height(m.viewportHeight()).
+		MaxHeight(m.viewportHeight()).
+		Render(m.focusView.View())
+
+	siblings, i := m.focusSiblings()
+	status := fmt.Sprintf("%3.f%%", scrollPercent(m.focusView))
+	if i >= 0 {
+		status += fmt.Sprintf(" | post %d of %d", i+1, len(siblings))
+	}
+	status += fmt.Sprintf(" (%s/%s previous/next post, %s back)",
+		m.keys.FocusPrev.Help().Key, m.keys.FocusNext.Help().Key, strings.Split(m.keys.Focus.Help().Key, "/")[0])
+	return lipgloss.JoinVertical(lipgloss.Left,
+		body,
+		style.SecondaryStyle.Copy().MaxWidth(m.w).Render(status),
+	)
+}
@@ -3,7 +3,10 @@ module hackerreader
 go 1.17
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/JohannesKaufmann/html-to-markdown v1.3.3
+	github.com/PuerkitoBio/goquery v1.5.1
+	github.com/atotto/clipboard v0.1.4
 	github.com/buger/jsonparser v1.1.1
 	github.com/charmbracelet/bubbles v0.10.3
 	github.com/charmbracelet/bubbletea v0.19.3
@@ -11,11 +14,12 @@ require (
 	github.com/charmbracelet/lipgloss v0.4.0
 	github.com/golang-collections/collections v0.0.0-20130729185459-604e922904d3
 	github.com/pkg/browser v0.0.0-20210911075715-681adbf594b8
+	github.com/yuin/goldmark v1.4.4
+	golang.org/x/net v0.0.0-20210614182718-04defd469f4e
 	golang.org/x/term v0.0.0-20210422114643-f5beecf764ed
 )
 
 require (
-	github.com/PuerkitoBio/goquery v1.5.1 // indirect
 	github.com/alecthomas/chroma v0.10.0 // indirect
 	github.com/andybalholm/cascadia v1.1.0 // indirect
 	github.com/aymerick/douceur v0.2.0 // indirect
@@ -32,8 +36,6 @@ require (
 	github.com/olekukonko/tablewriter v0.0.5 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	github.com/yuin/goldmark v1.4.4 // indirect
 	github.com/yuin/goldmark-emoji v1.0.1 // indirect
-	golang.org/x/net v0.0.0-20210614182718-04defd469f4e // indirect
 	golang.org/x/sys v0.0.0-20210616045830-e2b7044e8c71 // indirect
 )
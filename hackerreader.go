@@ -1,20 +1,30 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
+	"hackerreader/algolia"
+	"hackerreader/article"
+	"hackerreader/bookmarks"
+	"hackerreader/cache"
+	"hackerreader/config"
+	"hackerreader/hn"
+	"hackerreader/keys"
+	"hackerreader/killfile"
 	"hackerreader/posts"
-	"hackerreader/set"
+	"hackerreader/prompt"
+	"hackerreader/scheduler"
 	mySpinner "hackerreader/spinner"
 	"hackerreader/style"
-	"io"
-	"io/ioutil"
-	"net/http"
+	"hackerreader/visits"
 	"os"
+	"os/exec"
 	"strconv"
 	"strings"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -24,45 +34,122 @@ import (
 )
 
 const (
-	apiurl          = "https://hacker-news.firebaseio.com/v0"
-	itemUrl         = "https://news.ycombinator.com/item?id="
-	loadBacklogSize = 2
-	rootStoryId     = 0
-	maxWidth        = 135
+	itemUrl     = posts.ItemUrl
+	rootStoryId = 0
+)
+
+// what the text typed in the prompt is for
+const (
+	searchPrompt = iota
+	findPrompt
+	rulePrompt
+	exportPrompt
+	tagPrompt
 )
 
 type model struct {
-	w             int
-	cappedW       int
-	h             int
-	loaded        bool
-	toLoad        *set.Set
-	stories       map[int]*posts.Post
-	cursor        int
-	prevCursor    *stack.Stack
-	selected      *stack.Stack
-	spinner       *mySpinner.Spinner
-	collapseMain  bool
-	inFocus       int
-	inFocusCursor int
-	lastFrame     *string
-}
-
-func initialModel() model {
+	cfg            config.Config
+	keys           keys.KeyMap
+	showHelp       bool
+	client         hn.Client
+	searchClient   *algolia.Client
+	fetcher        article.Fetcher
+	cache          *cache.Cache // nil when caching is disabled
+	offline        bool
+	feed           hn.Feed
+	feeds          map[hn.Feed][]int
+	feedCursor     map[hn.Feed]int
+	w              int
+	cappedW        int
+	h              int
+	loaded         bool
+	loader         *scheduler.Scheduler
+	stories        map[int]*posts.Post
+	cursor         int
+	prevCursor     *stack.Stack
+	selected       *stack.Stack
+	spinner        *mySpinner.Spinner
+	collapseMain   bool
+	inFocus        int
+	focusView      viewport.Model // the post in focus mode
+	treeMode       bool
+	treeCursor     int // id of the post under the cursor (in tree mode)
+	collapsed      map[int]bool
+	storySort      sortMode
+	commentSort    sortMode
+	sorted         map[int]*sortedList // native order of the sorted lists
+	err            error               // last global failure (shown on the status line)
+	retry          tea.Cmd             // retries the last global failure
+	notice         string              // shown on the status line until the next key
+	prompt         *prompt.Prompt
+	promptKind     int
+	find           finder
+	killfile       *killfile.Killfile
+	showFiltered   bool // killfile disabled for now
+	killfileOpen   bool
+	killfileCursor int
+	visits         *visits.Visits
+	visit          visit
+	bookmarks      *bookmarks.Bookmarks
+	live           live
+	arrivals       map[int]bool // posts to mark as new when they arrive (see markArrival)
+	rankings       map[hn.Feed]*ranking
+	samples        map[int][]sample // points and comments of the stories over time (see trend.go)
+	links          linkPicker
+	reader         reader
+	exportId       int // post the export prompt is for (rootStoryId => the bookmarks)
+	tagId          int // post the tag prompt is for
+	lastFrame      *string
+	onScreen       *[]int // the posts on the last frame (see markOnScreen)
+}
+
+func initialModel(cfg config.Config, client hn.Client, searchClient *algolia.Client, fetcher article.Fetcher,
+	c *cache.Cache, kf *killfile.Killfile, v *visits.Visits, bm *bookmarks.Bookmarks) model {
+	feed, _ := hn.ParseFeed(cfg.Feed) // already validated
 	lastFrame := ""
 	s := mySpinner.New()
+	p := prompt.New()
+	keyMap, _ := keys.New(cfg.Keys) // already validated
 	initModel := model{
-		loaded:        false,
-		toLoad:        set.New(),
-		stories:       make(map[int]*posts.Post),
-		cursor:        0,
-		prevCursor:    stack.New(),
-		selected:      stack.New(),
-		spinner:       &s,
-		collapseMain:  false,
-		inFocus:       -1,
-		inFocusCursor: 0,
-		lastFrame:     &lastFrame, // first frame is empty
+		cfg:          cfg,
+		keys:         keyMap,
+		showHelp:     false,
+		client:       client,
+		searchClient: searchClient,
+		fetcher:      fetcher,
+		cache:        c,
+		offline:      cfg.Cache.Offline,
+		feed:         feed,
+		feeds:        make(map[hn.Feed][]int),
+		feedCursor:   make(map[hn.Feed]int),
+		loaded:       false,
+		loader:       scheduler.New(cfg.Loading.MaxFetches),
+		stories:      make(map[int]*posts.Post),
+		cursor:       0,
+		prevCursor:   stack.New(),
+		selected:     stack.New(),
+		spinner:      &s,
+		collapseMain: false,
+		inFocus:      -1,
+		treeMode:     false,
+		collapsed:    make(map[int]bool),
+		storySort:    sortNative,
+		commentSort:  sortNative,
+		sorted:       make(map[int]*sortedList),
+		prompt:       &p,
+		find:         finder{current: -1},
+		killfile:     kf,
+		showFiltered: false,
+		killfileOpen: false,
+		visits:       v,
+		bookmarks:    bm,
+		arrivals:     make(map[int]bool),
+		rankings:     make(map[hn.Feed]*ranking),
+		samples:      make(map[int][]sample),
+		links:        linkPicker{previews: make(map[string]*linkPreview)},
+		reader:       reader{pages: make(map[int]*readerPage)},
+		lastFrame:    &lastFrame, // first frame is empty
+		onScreen:     new([]int),
 	}
 	// term size
 	w, h, _ := term.GetSize(int(os.Stdout.Fd()))
@@ -76,88 +163,185 @@ func initialModel() model {
 	return initModel
 }
 
-type errMsg struct{ err error }
-
-func (e errMsg) Error() string { return e.err.Error() }
-
-type loadTickMsg struct{}
-
-func (m model) loadTick() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
-		return loadTickMsg{}
-	})
+type itemMsg struct {
+	id     int // the item that was fetched
+	ticket int
+	post   posts.Post
 }
 
-type topStoriesMsg struct {
-	stories []int
+func (m *model) fetchStory(job scheduler.Job) tea.Cmd {
+	return func() tea.Msg {
+		bodyBytes, err := m.client.Item(job.Ctx, job.Id)
+		if err != nil {
+			return itemErrMsg{stId: job.Id, ticket: job.Ticket, err: err}
+		}
+		st := posts.FromJSON(bodyBytes, m.spinner)
+		if st.Id != job.Id {
+			// e.g.: a body that isn't an item
+			err = fmt.Errorf("item %d: unexpected response from the API", job.Id)
+			return itemErrMsg{stId: job.Id, ticket: job.Ticket, err: err}
+		}
+		_ = m.cache.PutItem(job.Id, bodyBytes)
+		return itemMsg{id: job.Id, ticket: job.Ticket, post: st}
+	}
 }
 
-func fetchTopStories() tea.Msg {
-	c := &http.Client{Timeout: 10 * time.Second}
-	res, err := c.Get(apiurl + "/topstories.json")
-
-	if err != nil {
-		return errMsg{err}
+// Starts fetching as many of the queued posts as the scheduler allows
+func (m *model) dispatchLoads() tea.Cmd {
+	var batch []tea.Cmd
+	for _, job := range m.loader.Next() {
+		batch = append(batch, m.fetchStory(job))
 	}
-	defer func(Body io.ReadCloser) {
-		_ = Body.Close()
-	}(res.Body)
+	return tea.Batch(batch...)
+}
 
-	var data []int
-	err = json.NewDecoder(res.Body).Decode(&data)
-	if err != nil {
-		return errMsg{err}
+// A queued render is done (the posts have to be drawn again)
+type renderedMsg struct{}
+
+// Renders the posts' text that the last frames were missing (see
+// posts.PendingRenders), so the View doesn't have to. The ones left for lack
+// of workers go when these are done (renderedMsg).
+func renderPending() tea.Cmd {
+	var batch []tea.Cmd
+	for _, job := range posts.PendingRenders() {
+		job := job
+		batch = append(batch, func() tea.Msg {
+			job.Run()
+			return renderedMsg{}
+		})
 	}
-	return topStoriesMsg{stories: data}
+	return tea.Batch(batch...)
 }
 
-func (m *model) fetchStory(item string) tea.Cmd {
-	return func() tea.Msg {
-		c := &http.Client{Timeout: 10 * time.Second}
-		res, err := c.Get(apiurl + "/item/" + item + ".json")
-		if err != nil {
-			return errMsg{err}
-		}
-		defer func(Body io.ReadCloser) {
-			_ = Body.Close()
-		}(res.Body)
-
-		bodyBytes, _ := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return errMsg{err}
+// Cancels the loading of everything that isn't shown by the selected post or
+// prefetched for the hovered one (call after navigating)
+func (m *model) cancelLoads() {
+	parentStory := m.getPost(m.selected.Peek().(int))
+	keep := make(map[int]bool)
+	for _, stId := range parentStory.Kids {
+		keep[stId] = true
+	}
+	for _, stId := range parentStory.Parts {
+		keep[stId] = true
+	}
+	if m.cursor < parentStory.KidCount() {
+		for _, stId := range m.getPost(parentStory.Kids[m.cursor]).Kids {
+			keep[stId] = true
 		}
-		return posts.FromJSON(bodyBytes, m.spinner)
+	}
+	m.loader.CancelUnless(func(stId int) bool {
+		return keep[stId]
+	})
+	for _, list := range m.sorted {
+		// their loads might be gone => queued again when they're shown
+		list.queued = false
 	}
 }
 
 func (m model) Init() tea.Cmd {
 	return tea.Batch(
-		fetchTopStories,
+		m.fetchFeed(m.feed),
+		m.fetchUpdates(), // the first poll only sets where the session starts
 		m.spinner.Tick,
-		m.loadTick(),
 	)
 }
 
 func (m *model) setTermSize(w int, h int) {
 	m.w = w
 	m.h = h
-	m.cappedW = min(w, maxWidth)
+	m.cappedW = min(w, m.cfg.Layout.MaxWidth)
+}
+
+// Lines left for the full screen views (article reader and focus mode):
+// everything but the header (see headerView) and a footer line.
+func (m *model) viewportHeight() int {
+	return max(1, m.h-lipgloss.Height(m.headerView())-1)
+}
+
+// Keeps the full screen views' height in line with the header: its status
+// lines come and go (e.g.: a notice), and they scroll by the height.
+func (m *model) fitViewports() {
+	if m.inFocus > 0 {
+		m.focusView.Height = m.viewportHeight()
+		m.focusView.SetYOffset(m.focusView.YOffset) // in bounds
+	}
+	if m.reader.stId != 0 {
+		m.reader.viewport.Height = m.viewportHeight()
+		m.reader.viewport.SetYOffset(m.reader.viewport.YOffset)
+	}
 }
 
 // Returns the post/story and ques lazy loading if needed
 func (m *model) getPost(stId int) *posts.Post {
+	return m.loadPost(stId, scheduler.Visible)
+}
+
+// Same as getPost, but with the given loading priority
+func (m *model) loadPost(stId int, prio scheduler.Priority) *posts.Post {
 	st, exists := m.stories[stId]
 	if !exists {
+		if bodyBytes, cached := m.cache.Item(stId, m.offline); cached {
+			// no need to fetch it
+			if parsed := posts.FromJSON(bodyBytes, m.spinner); parsed.Id == stId {
+				return m.setPost(parsed)
+			}
+			// not the item (e.g.: a corrupted file) => as if it wasn't cached
+			_ = m.cache.DeleteItem(stId)
+		}
 		// create new post
 		newSt := posts.New(m.spinner)
 		m.stories[stId] = &newSt
+		if m.offline {
+			// can't be loaded
+			newSt.Missing = true
+			return &newSt
+		}
 		// queue for loading
-		m.toLoad.Insert(stId)
+		m.loader.Queue(stId, prio)
 		return &newSt
 	}
+	if st.IsLoading() || (st.Stale && st.Err == nil && !m.offline) {
+		// (re)queue => bumps the priority or undoes a cancel (a stale post is
+		// shown meanwhile)
+		m.loader.Queue(stId, prio)
+	}
 	return st
 }
 
+// Creates a pseudo post (still loading its kids) and goes in it. It's
+// selected like any other post, so going back leaves it.
+func (m *model) openPseudo(storytype string, title string) *posts.Post {
+	st := posts.NewPseudo(storytype, m.spinner)
+	st.Title = title
+	st.Descendants = -1 // still loading
+	m.stories[st.Id] = &st
+
+	m.inFocus = -1
+	m.treeMode = false
+	m.prevCursor.Push(m.cursor)
+	m.selected.Push(st.Id)
+	m.cursor = 0
+	m.cancelLoads()
+	return &st
+}
+
+// Stores a loaded post
+func (m *model) setPost(st posts.Post) *posts.Post {
+	m.stories[st.Id] = &st
+	m.applyFilter(&st)
+	m.markMatch(&st)
+	m.applyVisits(&st)
+	m.applyBookmarks(&st)
+	m.applyArrival(&st)
+	if st.Storytype == "poll" {
+		// load poll opts
+		for _, pollOptId := range st.Parts {
+			m.getPost(pollOptId) // will trigger loading if needed
+		}
+	}
+	return &st
+}
+
 func (m *model) moveCursor(newCursor int) {
 	st := m.getPost(m.selected.Peek().(int))
 	if newCursor < 0 {
@@ -168,57 +352,132 @@ func (m *model) moveCursor(newCursor int) {
 		newCursor = st.KidCount() - 1
 	}
 	// move the cursor
-	m.cursor = newCursor
+	m.cursor = max(0, newCursor)
 
 	// queues some children for loading
 	if st.KidCount() > 0 {
 		child := m.getPost(st.Kids[m.cursor])
-		for i := 0; i < loadBacklogSize && i < child.KidCount(); i++ {
+		for i := 0; i < m.cfg.Loading.Backlog && i < child.KidCount(); i++ {
 			grandChildId := child.Kids[i]
-			m.getPost(grandChildId) // will trigger loading if needed
+			m.loadPost(grandChildId, scheduler.Near) // will trigger loading if needed
+		}
+		// and prefetches the next ones in the list
+		for i := m.cursor + 1; i <= m.cursor+m.cfg.Loading.Prefetch && i < st.KidCount(); i++ {
+			m.loadPost(st.Kids[i], scheduler.Background)
 		}
 	}
 }
 
-func (m *model) focusKeyHandler(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+// Opens the URL with the configured browser command (or the system's browser)
+func (m *model) openURL(url string) {
+	if len(m.cfg.Browser) == 0 {
+		_ = browser.OpenURL(url)
+		return
+	}
+	args := strings.Fields(m.cfg.Browser)
+	cmd := exec.Command(args[0], append(args[1:], url)...)
+	if cmd.Start() == nil {
+		go func() {
+			_ = cmd.Wait()
+		}()
+	}
+}
+
+// Returns the id of the post under the cursor (if any)
+func (m *model) hoveredId() (int, bool) {
+	parentStory := m.getPost(m.selected.Peek().(int))
+	if m.treeMode {
+		nodes := m.flattenTree(parentStory)
+		if len(nodes) == 0 {
+			return 0, false
+		}
+		return nodes[m.treeCursorIndex(nodes)].stId, true
+	}
+	if !parentStory.HasKids() {
+		return 0, false
+	}
+	return parentStory.Kids[m.cursor], true
+}
+
+func (m *model) openPrompt(kind int, promptStr string, placeholder string) {
+	m.promptKind = kind
+	m.prompt.Open(promptStr, placeholder)
+}
+
+func (m *model) promptKeyHandler(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
-	case "ctrl+c", "q": // quit
+	case "ctrl+c": // quit
 		return m, tea.Quit
-	case "down", "j":
-		m.inFocusCursor++
-	case "up", "k":
-		m.inFocusCursor = max(0, m.inFocusCursor-1)
-	case "f":
-		m.inFocus = -1
-		m.inFocusCursor = 0
+	case "esc": // give up
+		m.prompt.Close()
+	case "enter": // submit
+		switch m.promptKind {
+		case searchPrompt:
+			return m, m.submitSearch(m.prompt.Value())
+		case findPrompt:
+			m.submitFind(m.prompt.Value())
+		case rulePrompt:
+			m.submitRule(m.prompt.Value())
+		case exportPrompt:
+			return m, m.submitExport(m.prompt.Value())
+		case tagPrompt:
+			m.submitTags(m.prompt.Value())
+		}
+	default:
+		return m, m.prompt.Update(msg)
 	}
 	return m, nil
 }
 
 func (m *model) keyHandler(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.prompt.IsOpen() {
+		return m.promptKeyHandler(msg)
+	}
+	if m.showHelp {
+		// any key closes the help (q included, so only ctrl+c quits from here)
+		m.showHelp = false
+		if msg.Type == tea.KeyCtrlC {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+	if m.killfileOpen {
+		return m.killfileKeyHandler(msg)
+	}
+	if m.links.open {
+		return m.linksKeyHandler(msg)
+	}
+	if m.reader.stId != 0 {
+		return m.readerKeyHandler(msg)
+	}
 	if m.inFocus > 0 {
 		return m.focusKeyHandler(msg)
 	}
+	if m.treeMode && m.treeKeyHandler(msg) {
+		return m, nil
+	}
 
-	switch msg.String() {
-	case "ctrl+c", "q": // quit
+	switch {
+	case key.Matches(msg, m.keys.Quit):
 		return m, tea.Quit
-	case "g", "home":
+	case key.Matches(msg, m.keys.Help):
+		m.showHelp = true
+	case key.Matches(msg, m.keys.Top):
 		m.moveCursor(0)
-	case "G", "alt+[":
+	case key.Matches(msg, m.keys.Bottom):
 		m.moveCursor(-1)
-	case "pgup":
+	case key.Matches(msg, m.keys.PageUp):
 		m.moveCursor(m.cursor - min(10, m.cursor))
-	case "pgdown":
+	case key.Matches(msg, m.keys.PageDown):
 		m.moveCursor(m.cursor + 10)
-	case "down", "j": // point down
+	case key.Matches(msg, m.keys.Down): // point down
 		m.moveCursor(m.cursor + 1)
-	case "up", "k": // point up
+	case key.Matches(msg, m.keys.Up): // point up
 		m.moveCursor(max(m.cursor-1, 0))
-	case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
+	case key.Matches(msg, m.keys.Jump):
 		newCursor, _ := strconv.Atoi(msg.String())
 		m.moveCursor(newCursor)
-	case "enter", "right", "l": // go in
+	case key.Matches(msg, m.keys.Select): // go in
 		m.collapseMain = false // disable main collapsing
 		parentStory := m.getPost(m.selected.Peek().(int))
 		if parentStory.HasKids() {
@@ -229,58 +488,149 @@ func (m *model) keyHandler(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				// loaded => we can go in
 				m.prevCursor.Push(m.cursor) // save previous state for when we go back
 				m.selected.Push(stId)
-				m.cursor = 0 // go in and load kids (if needed)
+				m.cursor = 0
+				m.cancelLoads()
+				m.moveCursor(m.cursor) // go in and load kids (if needed)
 			}
 		}
-	case "escape", "left", "h": // go back
+	case key.Matches(msg, m.keys.Back): // go back
 		m.collapseMain = false // disable main collapsing
 		// recover previous state
 		if m.selected.Len() > 1 {
 			// we're nested (rootStory can't be popped)
+			m.treeMode = false
 			m.cursor = m.prevCursor.Pop().(int)
 			m.selected.Pop()
+			m.cancelLoads()
+			m.moveCursor(m.cursor)
 		}
-	case " ": // hide/unhide given story
-		parentStory := m.getPost(m.selected.Peek().(int))
-		if parentStory.HasKids() {
-			st := m.getPost(parentStory.Kids[m.cursor])
+	case key.Matches(msg, m.keys.Hide): // hide/unhide given story
+		if stId, ok := m.hoveredId(); ok {
+			st := m.getPost(stId)
 			if st.IsLoaded() {
-				st.ToggleHidden()
+				m.toggleHidden(st)
 			}
 		}
-	case "o": // open story URL is browser
+	case key.Matches(msg, m.keys.OpenUrl): // open story URL is browser
 		parentStory := m.getPost(m.selected.Peek().(int))
 
 		var targetStory *posts.Post
-		if m.selected.Len() > 1 {
+		if m.selected.Len() > 1 && !parentStory.IsPseudo() {
 			targetStory = parentStory
-		} else if parentStory.HasKids() {
-			targetStory = m.getPost(parentStory.Kids[m.cursor])
+		} else if stId, ok := m.hoveredId(); ok {
+			targetStory = m.getPost(stId)
 		}
 
 		if targetStory != nil && targetStory.HasUrl() {
-			_ = browser.OpenURL(targetStory.Url)
+			m.openURL(targetStory.Url)
 		}
-	case "O": // open story in browser
-		parentStory := m.getPost(m.selected.Peek().(int))
-		if parentStory.HasKids() {
-			targetSt := m.getPost(parentStory.Kids[m.cursor])
-			_ = browser.OpenURL(itemUrl + strconv.Itoa(targetSt.Id))
+	case key.Matches(msg, m.keys.OpenItem): // open story in browser
+		if stId, ok := m.hoveredId(); ok {
+			m.openURL(itemUrl + strconv.Itoa(stId))
+		}
+	case key.Matches(msg, m.keys.NextFeed):
+		return m, m.cycleFeed(1)
+	case key.Matches(msg, m.keys.PrevFeed):
+		return m, m.cycleFeed(-1)
+	case key.Matches(msg, m.keys.User) && !m.offline: // open the hovered post's author profile
+		targetStory := m.getPost(m.selected.Peek().(int))
+		if stId, ok := m.hoveredId(); ok {
+			targetStory = m.getPost(stId)
+		}
+		if targetStory.IsLoaded() && len(targetStory.By) > 0 {
+			return m, m.openUser(targetStory.By)
 		}
-	case "F":
+	case key.Matches(msg, m.keys.Retry): // retry everything that failed
+		return m, m.retryAll()
+	case key.Matches(msg, m.keys.Search) && !m.offline:
+		m.openPrompt(searchPrompt, "Search: ", searchPlaceholder)
+	case key.Matches(msg, m.keys.Find):
+		m.openPrompt(findPrompt, "Find in thread: ", findPlaceholder)
+	case key.Matches(msg, m.keys.FindNext):
+		m.nextMatch(1)
+	case key.Matches(msg, m.keys.FindPrev):
+		m.nextMatch(-1)
+	case key.Matches(msg, m.keys.Killfile):
+		m.openKillfile()
+	case key.Matches(msg, m.keys.ShowFiltered):
+		m.toggleShowFiltered()
+	case key.Matches(msg, m.keys.CollapseMain):
 		m.collapseMain = !m.collapseMain
-	case "f": // enter focus mode on current hover
-		parent := m.getPost(m.selected.Peek().(int))
-		if parent.KidCount() > 0 {
-			childId := parent.Kids[m.cursor]
-			m.inFocus = childId
+	case key.Matches(msg, m.keys.Focus): // enter focus mode on current hover
+		if childId, ok := m.hoveredId(); ok {
+			m.openFocus(childId)
+		}
+	case key.Matches(msg, m.keys.Tree):
+		m.toggleTreeMode()
+	case key.Matches(msg, m.keys.Sort):
+		m.cycleSort()
+	case key.Matches(msg, m.keys.Links):
+		m.openLinks()
+		return m, m.fetchPreview()
+	case key.Matches(msg, m.keys.Article):
+		if stId, ok := m.articleTarget(); ok {
+			return m, m.openArticle(stId)
+		}
+	case key.Matches(msg, m.keys.Export):
+		if m.feed == savedFeed && m.selected.Len() == 1 {
+			// the Saved feed itself => the bookmarks
+			m.openExport(rootStoryId)
+		} else if stId, ok := m.exportTarget(); ok {
+			m.openExport(stId)
 		}
+	case key.Matches(msg, m.keys.NextNew):
+		m.nextNew()
+	case key.Matches(msg, m.keys.Bookmark):
+		if st, ok := m.bookmarkTarget(); ok {
+			m.toggleBookmark(st)
+		}
+	case key.Matches(msg, m.keys.Tag):
+		if st, ok := m.bookmarkTarget(); ok {
+			m.openTags(st)
+		}
+	case key.Matches(msg, m.keys.LivePause):
+		return m, m.toggleLive()
+	case key.Matches(msg, m.keys.Refresh):
+		return m, m.refresh()
 	}
 
 	return m, nil
 }
 
 func (m *model) MouseHandler(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.showHelp || m.killfileOpen || m.links.open {
+		// overlays don't scroll (and the list behind them mustn't move)
+		return m, nil
+	}
+	if m.reader.stId != 0 {
+		switch msg.Type {
+		case tea.MouseWheelDown:
+			m.reader.viewport.LineDown(3)
+		case tea.MouseWheelUp:
+			m.reader.viewport.LineUp(3)
+		}
+		return m, nil
+	}
+	if m.inFocus > 0 {
+		switch msg.Type {
+		case tea.MouseWheelDown:
+			m.focusView.LineDown(3)
+		case tea.MouseWheelUp:
+			m.focusView.LineUp(3)
+		}
+		return m, nil
+	}
+	if m.treeMode {
+		nodes := m.flattenTree(m.getPost(m.selected.Peek().(int)))
+		switch msg.Type {
+		case tea.MouseWheelDown:
+			m.moveTreeCursor(nodes, m.treeCursorIndex(nodes)+1)
+		case tea.MouseWheelUp:
+			m.moveTreeCursor(nodes, m.treeCursorIndex(nodes)-1)
+		}
+		return m, nil
+	}
+
 	switch msg.Type {
 	case tea.MouseWheelDown:
 		m.moveCursor(m.cursor + 1)
@@ -291,18 +641,44 @@ func (m *model) MouseHandler(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// A global fetch went fine => the last failure (if any) is no longer relevant
+func (m *model) clearErr() {
+	m.err = nil
+	m.retry = nil
+}
+
 func (m *model) setRedraw() {
 	*m.lastFrame = ""
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	ret, cmd := m.handleMsg(msg)
+	// a story might have been opened or left
+	m.trackVisit()
+	// a status line might have come or gone
+	m.fitViewports()
+	if resorts(msg) {
+		// posts might have arrived (or the sort changed) => keep the list sorted
+		m.sortShown()
+	}
+	// whatever got queued meanwhile starts loading (and rendering) right away
+	return ret, tea.Batch(cmd, m.dispatchLoads(), renderPending())
+}
+
+func (m *model) handleMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.setTermSize(msg.Width, msg.Height)
+		if page, reading := m.reader.pages[m.reader.stId]; reading {
+			m.setReaderContent(page)
+		}
+		m.setFocusContent()
 		m.setRedraw()
 		return m, nil
 	case tea.KeyMsg:
 		// handle keyboard
+		m.notice = ""
+		m.clearUpdated()
 		m.setRedraw()
 		return m.keyHandler(msg)
 	case tea.MouseMsg:
@@ -310,32 +686,84 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.setRedraw()
 		return m.MouseHandler(msg)
 	case errMsg:
-		fmt.Println(msg)
-		return m, tea.Quit
-	case topStoriesMsg:
-		m.loaded = true
-		rootStory := m.getPost(rootStoryId)
-		rootStory.Kids = msg.stories
-		rootStory.Descendants = len(msg.stories)
+		m.err = msg.err
+		m.retry = msg.retry
 		m.setRedraw()
 		return m, nil
-	case loadTickMsg:
-		var batch []tea.Cmd
-		for stId := range m.toLoad.Hash {
-			stIdStr := strconv.Itoa(stId.(int))
-			batch = append(batch, m.fetchStory(stIdStr))
-		}
-		m.toLoad.Clear()
-		batch = append(batch, m.loadTick()) // queue next tick
-		return m, tea.Batch(batch...)
-	case posts.Post:
-		m.stories[msg.Id] = &msg
-		if msg.Storytype == "poll" {
-			// load poll opts
-			for _, pollOptId := range msg.Parts {
-				m.getPost(pollOptId) // will trigger loading if needed
-			}
+	case itemErrMsg:
+		if !m.loader.Done(msg.stId, msg.ticket) {
+			// cancelled => not an error
+			return m, nil
+		}
+		cmd := m.setItemErr(msg)
+		m.setRedraw()
+		return m, cmd
+	case retryItemMsg:
+		m.retryItem(msg.stId)
+		return m, nil
+	case feedMsg:
+		m.clearErr()
+		m.feeds[msg.feed] = msg.stories
+		m.rankFeed(msg.feed, msg.stories)
+		if msg.feed == m.feed {
+			m.loaded = true
+			m.setRootStories(msg.stories)
+			m.setRedraw()
+		}
+		return m, nil
+	case userMsg:
+		m.clearErr()
+		m.setUser(msg)
+		m.setRedraw()
+		return m, nil
+	case searchMsg:
+		m.clearErr()
+		m.setSearchResults(msg)
+		m.setRedraw()
+		return m, nil
+	case itemMsg:
+		if !m.loader.Done(msg.id, msg.ticket) {
+			// cancelled (or replaced by a newer fetch) => stale
+			return m, nil
+		}
+		m.sampleTrend(&msg.post)
+		m.loadTreeKids(m.setPost(msg.post))
+		m.setFocusContent() // might be the post in focus (or one of its poll options)
+		m.setRedraw()
+		return m, nil
+	case renderedMsg:
+		m.setFocusContent()
+		m.setRedraw()
+		return m, nil
+	case articleMsg:
+		m.setArticle(msg)
+		m.setRedraw()
+		return m, nil
+	case linkPreviewMsg:
+		m.setLinkPreview(msg)
+		m.setRedraw()
+		return m, nil
+	case exportMsg:
+		m.setExport(msg)
+		m.setRedraw()
+		return m, nil
+	case pollMsg:
+		if msg.gen != m.live.gen {
+			// paused (or resumed) meanwhile
+			return m, nil
 		}
+		return m, m.fetchUpdates()
+	case updatesMsg:
+		cmd := m.setUpdates(msg)
+		m.setRedraw()
+		return m, cmd
+	case refreshedMsg:
+		cmd := m.setRefreshed(msg)
+		m.setRedraw()
+		return m, cmd
+	case refreshMsg:
+		m.clearErr()
+		m.setRefresh(msg)
 		m.setRedraw()
 		return m, nil
 	case spinner.TickMsg:
@@ -362,12 +790,26 @@ func (m *model) listItemView(parentStory *posts.Post, i int, w int) string {
 	if highlight {
 		cursor = style.Checkmark(">")
 	}
-	row := lipgloss.JoinHorizontal(lipgloss.Top, orderI, cursor)
+	// the front page's trends (see trend.go)
+	move, trend := "", ""
+	if parentStory.Id == rootStoryId {
+		if m.ranked(m.feed) {
+			move = lipgloss.NewStyle().Width(rankMoveW).Render(m.rankMoveView(m.feed, st.Id))
+		}
+		if trend = m.trendView(st.Id); len(trend) > 0 {
+			trend = lipgloss.NewStyle().PaddingLeft(1).Render(trend)
+		}
+	}
+	row := lipgloss.JoinHorizontal(lipgloss.Top, orderI, cursor, move)
 	// 2 for borders + 1 for end padding
-	remainingW := w - lipgloss.Width(row) - 3
+	remainingW := w - lipgloss.Width(row) - lipgloss.Width(trend) - 3
 	listItemStr := st.View(highlight, false, remainingW, m.stories)
+	if len(trend) > 0 {
+		// the sparklines go on the right
+		listItemStr = lipgloss.NewStyle().Width(remainingW).Render(listItemStr)
+	}
 	itemStr := lipgloss.JoinHorizontal(lipgloss.Top,
-		cursor, orderI, listItemStr)
+		cursor, orderI, move, listItemStr, trend)
 
 	if i == parentStory.KidCount()-1 {
 		// last item
@@ -390,15 +832,158 @@ func (m *model) listItemView(parentStory *posts.Post, i int, w int) string {
 	return listItemStyle.Render(itemStr)
 }
 
+func (m *model) helpView() string {
+	h := help.New()
+	h.Width = m.cappedW - 2
+	h.Styles.FullKey = style.PrimaryStyle.Copy().Bold(true)
+	h.Styles.FullDesc = style.SecondaryStyle
+	h.Styles.FullSeparator = style.SecondaryStyle
+	title := style.PrimaryStyle.Copy().Bold(true)
+	return style.MainItem.
+		Width(m.cappedW - 2).
+		Render(lipgloss.JoinVertical(lipgloss.Left,
+			title.Render("Keybindings"),
+			h.FullHelpView(m.keys.FullHelp()),
+			"",
+			title.Render("Tree mode"),
+			h.FullHelpView(m.keys.TreeHelp()),
+			"",
+			title.Render("Focus mode"),
+			h.FullHelpView(m.keys.FocusHelp()),
+			"",
+			title.Render("Article reader"),
+			h.FullHelpView(m.keys.ReaderHelp()),
+			"",
+			style.SecondaryStyle.Render("Press any key to close"),
+		))
+}
+
+// Renders the items of a list, keeping the one under the cursor as close to
+// the middle of the available height (maxH) as possible. Also returns the
+// items shown whole (and the one under the cursor, even if it doesn't fit):
+// the ones cut by the edges weren't really seen.
+func centeredList(n int, cursor int, maxH int, itemView func(i int) string) (string, []int) {
+	type span struct{ i, top, bot int } // lines from the top of the cursor's item
+	remainingH := maxH
+	itemList := itemView(cursor)
+	cursorTop := 0
+	cursorBot := lipgloss.Height(itemList)
+	remainingH -= cursorBot
+	var spans []span
+	belowH := cursorBot
+	for offset := 1; offset < max(cursor, n) && remainingH > 0; offset++ {
+		var i int
+		// up
+		i = cursor - offset
+		if i >= 0 {
+			itemStr := itemView(i)
+			itemStrHeight := lipgloss.Height(itemStr)
+			spans = append(spans, span{i, -cursorTop - itemStrHeight, -cursorTop})
+			cursorTop += itemStrHeight
+			cursorBot += itemStrHeight
+			remainingH -= itemStrHeight
+			itemList = lipgloss.JoinVertical(lipgloss.Left, itemStr, itemList)
+		}
+		// down
+		i = cursor + offset
+		if i < n {
+			itemStr := itemView(i)
+			itemStrHeight := lipgloss.Height(itemStr)
+			spans = append(spans, span{i, belowH, belowH + itemStrHeight})
+			belowH += itemStrHeight
+			remainingH -= itemStrHeight
+			itemList = lipgloss.JoinVertical(lipgloss.Left, itemList, itemStr)
+		}
+	}
+	aboveH := cursorTop
+
+	itemListSplit := strings.Split(itemList, "\n")
+	changed := 2
+	alternator := 0
+	for cursorBot-cursorTop < maxH && changed > 0 {
+		if alternator == 0 {
+			if cursorTop > 0 {
+				changed++
+				cursorTop--
+			} else {
+				changed--
+			}
+		} else {
+			if cursorBot < len(itemListSplit) {
+				changed++
+				cursorBot++
+			} else {
+				changed--
+			}
+		}
+		alternator = (alternator + 1) % 2
+	}
+	if cursorBot-cursorTop > maxH {
+		// special case where only hovered post fits and is too big
+		cursorBot -= cursorBot - cursorTop - maxH
+	}
+
+	shown := []int{cursor}
+	for _, s := range spans {
+		if s.top+aboveH >= cursorTop && s.bot+aboveH <= cursorBot {
+			shown = append(shown, s.i)
+		}
+	}
+	return strings.Join(itemListSplit[cursorTop:cursorBot], "\n"), shown
+}
+
+// The title bar and the status lines under it (error, notice, prompt or find
+// query), on top of every view.
+func (m *model) headerView() string {
+	title := "HackerReader - " + feedTitle(m.feed)
+	if m.offline {
+		title += " (offline)"
+	}
+	if m.showFiltered {
+		title += " (showing filtered)"
+	}
+	if m.live.paused {
+		title += " (updates paused)"
+	}
+	title += m.sortView()
+	ret := style.TitleBar.Width(m.w).Render(title)
+	if m.err != nil {
+		ret = lipgloss.JoinVertical(lipgloss.Left, ret,
+			style.ErrorStyle.Copy().MaxWidth(m.w).Render(
+				fmt.Sprintf("Error: %s (%s to retry)", m.err, m.keys.Retry.Help().Key)))
+	}
+	if len(m.notice) > 0 {
+		ret = lipgloss.JoinVertical(lipgloss.Left, ret,
+			style.SecondaryStyle.Copy().MaxWidth(m.w).Render(m.notice))
+	}
+	if m.prompt.IsOpen() {
+		ret = lipgloss.JoinVertical(lipgloss.Left, ret, m.prompt.View(m.w))
+	} else if len(m.find.query) > 0 {
+		ret = lipgloss.JoinVertical(lipgloss.Left, ret, m.findView())
+	}
+	return ret
+}
+
 func (m model) View() string {
 	if len(*m.lastFrame) > 0 {
 		return *m.lastFrame
 	}
+	*m.onScreen = (*m.onScreen)[:0]
 
-	// top bar
-	remainingH := m.h
-	ret := style.TitleBar.Width(m.w).Render("HackerReader")
-	remainingH -= lipgloss.Height(ret)
+	ret := m.headerView()
+	remainingH := m.h - lipgloss.Height(ret)
+	if m.showHelp {
+		return lipgloss.JoinVertical(lipgloss.Left, ret, m.helpView())
+	}
+	if m.killfileOpen {
+		return lipgloss.JoinVertical(lipgloss.Left, ret, m.killfileView())
+	}
+	if m.links.open {
+		return lipgloss.JoinVertical(lipgloss.Left, ret, m.linksView())
+	}
+	if m.reader.stId != 0 {
+		return lipgloss.JoinVertical(lipgloss.Left, ret, m.readerView())
+	}
 	if !m.loaded {
 		// app not loaded yet
 		return lipgloss.JoinVertical(lipgloss.Left,
@@ -411,13 +996,8 @@ func (m model) View() string {
 
 	if m.inFocus > 0 {
 		// in focus mode
-		focusedSt := m.getPost(m.inFocus)
-		focusedStr := focusedSt.View(false, true, m.cappedW, m.stories)
-		focusedStrSplit := strings.Split(focusedStr, "\n")
-		return lipgloss.JoinVertical(lipgloss.Left,
-			ret,
-			strings.Join(focusedStrSplit[min(m.inFocusCursor, len(focusedStrSplit)-1):], "\n"),
-		)
+		*m.onScreen = append(*m.onScreen, m.inFocus)
+		return lipgloss.JoinVertical(lipgloss.Left, ret, m.focusModeView())
 	}
 
 	// current story (if any selected (can be root))
@@ -428,6 +1008,7 @@ func (m model) View() string {
 			mainItemStr = style.PrimaryStyle.Copy().Bold(true).Render("Collapsed story")
 		} else {
 			mainItemStr = parentStory.View(true, true, m.cappedW-4, m.stories)
+			*m.onScreen = append(*m.onScreen, parentStory.Id)
 		}
 
 		mainItemStr = style.MainItem.
@@ -446,75 +1027,133 @@ func (m model) View() string {
 		return ret
 	}
 	// iterate over children
-	maxItemListH := remainingH
-	itemList := m.listItemView(parentStory, m.cursor, m.cappedW)
-	cursorTop := 0
-	cursorBot := lipgloss.Height(itemList)
-	remainingH -= cursorBot
-	for offset := 1; offset < max(m.cursor, len(parentStory.Kids)) && remainingH > 0; offset++ {
-		var i int
-		// up
-		i = m.cursor - offset
-		if i >= 0 {
-			itemStr := m.listItemView(parentStory, i, m.cappedW)
-			itemStrHeight := lipgloss.Height(itemStr)
-			cursorTop += itemStrHeight
-			cursorBot += itemStrHeight
-			remainingH -= itemStrHeight
-			itemList = lipgloss.JoinVertical(lipgloss.Left, itemStr, itemList)
-		}
-		// down
-		i = m.cursor + offset
-		if i < parentStory.KidCount() {
-			itemStr := m.listItemView(parentStory, i, m.cappedW)
-			remainingH -= lipgloss.Height(itemStr)
-			itemList = lipgloss.JoinVertical(lipgloss.Left, itemList, itemStr)
-		}
-	}
-
-	itemListSplit := strings.Split(itemList, "\n")
-	changed := 2
-	alternator := 0
-	for cursorBot-cursorTop < maxItemListH && changed > 0 {
-		if alternator == 0 {
-			if cursorTop > 0 {
-				changed++
-				cursorTop--
-			} else {
-				changed--
-			}
-		} else {
-			if cursorBot < len(itemListSplit) {
-				changed++
-				cursorBot++
-			} else {
-				changed--
-			}
+	var itemList string
+	if m.treeMode {
+		itemList = m.treeView(parentStory, remainingH)
+	} else {
+		var shown []int
+		itemList, shown = centeredList(parentStory.KidCount(), m.cursor, remainingH, func(i int) string {
+			return m.listItemView(parentStory, i, m.cappedW)
+		})
+		for _, i := range shown {
+			*m.onScreen = append(*m.onScreen, parentStory.Kids[i])
 		}
-		alternator = (alternator + 1) % 2
-	}
-	if cursorBot-cursorTop > maxItemListH {
-		// special case where only hovered post fits and is too big
-		cursorBot -= cursorBot - cursorTop - maxItemListH
 	}
 
-	ret = lipgloss.JoinVertical(
-		lipgloss.Left,
-		ret,
-		strings.Join(itemListSplit[cursorTop:cursorBot], "\n"),
-	)
+	ret = lipgloss.JoinVertical(lipgloss.Left, ret, itemList)
 	*m.lastFrame = ret // save last frame
 	return ret
 }
 
+// Returns nil when caching is disabled
+func openCache(cfg config.Config) (*cache.Cache, error) {
+	if !cfg.Cache.Enabled && !cfg.Cache.Offline {
+		return nil, nil
+	}
+	cacheDir := cfg.Cache.Dir
+	if len(cacheDir) == 0 {
+		var err error
+		cacheDir, err = cache.DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cache.New(cacheDir)
+}
+
 func main() {
+	cfg, err := parseFlags()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	c, err := openCache(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't open the cache:", err)
+		os.Exit(1)
+	}
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(cfg, c, flag.Args()))
+	}
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		// e.g.: piped => the stories of the feed, as text
+		os.Exit(runCommand(cfg, c, []string{cfg.Feed}))
+	}
+
+	killfilePath := cfg.Killfile
+	if len(killfilePath) == 0 {
+		killfilePath, err = killfile.DefaultPath()
+	}
+	var kf *killfile.Killfile
+	if err == nil {
+		kf, err = killfile.Load(killfilePath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't load the killfile:", err)
+		os.Exit(1)
+	}
+
+	visitsPath := cfg.Visits
+	if len(visitsPath) == 0 {
+		visitsPath, err = visits.DefaultPath()
+	}
+	var v *visits.Visits
+	if err == nil {
+		v, err = visits.Load(visitsPath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't load the visits:", err)
+		os.Exit(1)
+	}
+
+	bookmarksPath := cfg.Bookmarks
+	if len(bookmarksPath) == 0 {
+		bookmarksPath, err = bookmarks.DefaultPath()
+	}
+	var bm *bookmarks.Bookmarks
+	if err == nil {
+		bm, err = bookmarks.Load(bookmarksPath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't load the bookmarks:", err)
+		os.Exit(1)
+	}
+
+	theme := style.DefaultTheme()
+	if len(cfg.Theme) > 0 {
+		theme, err = style.LoadTheme(cfg.Theme)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't load the theme:", err)
+			os.Exit(1)
+		}
+	}
+	style.Apply(theme)
+
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if cfg.Mouse {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
 	p := tea.NewProgram(
-		initialModel(),
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
+		initialModel(
+			cfg,
+			hn.New(cfg.Http.ApiUrl, cfg.Http.Timeout),
+			algolia.New(cfg.Http.SearchUrl, cfg.Http.Timeout),
+			article.NewFetcher(cfg.Http.Timeout),
+			c,
+			kf,
+			v,
+			bm,
+		),
+		opts...,
 	)
 	if err := p.Start(); err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
 	}
+	// what was seen since the last story was left
+	if err := v.Save(); err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't save the visits:", err)
+		os.Exit(1)
+	}
 }
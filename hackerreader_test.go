@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCenteredListShown(t *testing.T) {
+	// items of 3 lines, the cursor on the middle one
+	item := func(i int) string {
+		return strings.TrimSuffix(strings.Repeat(strconv.Itoa(i)+"\n", 3), "\n")
+	}
+	for _, test := range []struct {
+		maxH  int
+		shown []int
+	}{
+		{maxH: 2, shown: []int{2}},       // the cursor's item doesn't even fit
+		{maxH: 7, shown: []int{2}},       // its neighbours are cut
+		{maxH: 8, shown: []int{1, 2}},    // only the one below is cut
+		{maxH: 9, shown: []int{1, 2, 3}}, // all whole
+		{maxH: 15, shown: []int{0, 1, 2, 3, 4}},
+	} {
+		list, shown := centeredList(5, 2, test.maxH, item)
+		if h := strings.Count(list, "\n") + 1; h != min(test.maxH, 15) {
+			t.Errorf("maxH %d: %d lines shown", test.maxH, h)
+		}
+		sort.Ints(shown)
+		if !reflect.DeepEqual(shown, test.shown) {
+			t.Errorf("maxH %d: shown %v, want %v", test.maxH, shown, test.shown)
+		}
+	}
+}
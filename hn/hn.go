@@ -0,0 +1,178 @@
+package hn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "https://hacker-news.firebaseio.com/v0"
+	DefaultTimeout = 10 * time.Second
+)
+
+var (
+	// the API answers "null" for items/users that don't exist
+	ErrNotFound = errors.New("not found")
+	// all clients share the same transport => connections get reused
+	transport = http.DefaultTransport.(*http.Transport).Clone()
+)
+
+// Feed is one of the story lists the API serves (e.g.: /topstories.json).
+type Feed string
+
+const (
+	TopFeed  Feed = "top"
+	NewFeed  Feed = "new"
+	BestFeed Feed = "best"
+	AskFeed  Feed = "ask"
+	ShowFeed Feed = "show"
+	JobFeed  Feed = "job"
+)
+
+// Feeds lists every feed, in the order they are cycled through.
+var Feeds = []Feed{TopFeed, NewFeed, BestFeed, AskFeed, ShowFeed, JobFeed}
+
+func ParseFeed(name string) (Feed, error) {
+	for _, feed := range Feeds {
+		if string(feed) == strings.ToLower(name) {
+			return feed, nil
+		}
+	}
+	return "", fmt.Errorf("unknown feed %q (expected one of top, new, best, ask, show or job)", name)
+}
+
+// Title is the human friendly name of the feed.
+func (f Feed) Title() string {
+	switch f {
+	case NewFeed:
+		return "New stories"
+	case BestFeed:
+		return "Best stories"
+	case AskFeed:
+		return "Ask HN"
+	case ShowFeed:
+		return "Show HN"
+	case JobFeed:
+		return "Jobs"
+	default:
+		return "Top stories"
+	}
+}
+
+// Client is what the reader uses to talk to the HackerNews API.
+// Items are returned as raw JSON so they can go through posts.FromJSON.
+type Client interface {
+	TopStories(ctx context.Context) ([]int, error)
+	Stories(ctx context.Context, feed Feed) ([]int, error)
+	Item(ctx context.Context, id int) ([]byte, error)
+	User(ctx context.Context, id string) (User, error)
+	MaxItem(ctx context.Context) (int, error)
+	Updates(ctx context.Context) (Updates, error)
+}
+
+type User struct {
+	Id        string `json:"id"`        // The user's unique username.
+	Created   int    `json:"created"`   // Creation date of the user, in Unix Time.
+	Karma     int    `json:"karma"`     // The user's karma.
+	About     string `json:"about"`     // The user's optional self-description (HTML).
+	Submitted []int  `json:"submitted"` // List of the user's stories, polls and comments.
+}
+
+type Updates struct {
+	Items    []int    `json:"items"`    // Ids of the items that changed recently.
+	Profiles []string `json:"profiles"` // Usernames of the profiles that changed recently.
+}
+
+type client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// New creates a client for the API at baseURL (DefaultBaseURL if empty).
+func New(baseURL string, timeout time.Duration) Client {
+	if len(baseURL) == 0 {
+		baseURL = DefaultBaseURL
+	}
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+	}
+}
+
+func (c *client) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", path, res.Status)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if string(body) == "null" {
+		return nil, fmt.Errorf("GET %s: %w", path, ErrNotFound)
+	}
+	return body, nil
+}
+
+func (c *client) getJSON(ctx context.Context, path string, v interface{}) error {
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (c *client) TopStories(ctx context.Context) ([]int, error) {
+	return c.Stories(ctx, TopFeed)
+}
+
+func (c *client) Stories(ctx context.Context, feed Feed) ([]int, error) {
+	var ids []int
+	err := c.getJSON(ctx, "/"+string(feed)+"stories.json", &ids)
+	return ids, err
+}
+
+func (c *client) Item(ctx context.Context, id int) ([]byte, error) {
+	return c.get(ctx, "/item/"+strconv.Itoa(id)+".json")
+}
+
+func (c *client) User(ctx context.Context, id string) (User, error) {
+	var user User
+	err := c.getJSON(ctx, "/user/"+url.PathEscape(id)+".json", &user)
+	return user, err
+}
+
+func (c *client) MaxItem(ctx context.Context) (int, error) {
+	var id int
+	err := c.getJSON(ctx, "/maxitem.json", &id)
+	return id, err
+}
+
+func (c *client) Updates(ctx context.Context) (Updates, error) {
+	var updates Updates
+	err := c.getJSON(ctx, "/updates.json", &updates)
+	return updates, err
+}
@@ -0,0 +1,117 @@
+package hn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A stub of the API: path => body (a missing path is a 404).
+func stubAPI(t *testing.T, bodies map[string]string) Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, exists := bodies[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return New(srv.URL+"/", DefaultTimeout) // the trailing slash is trimmed
+}
+
+func TestItem(t *testing.T) {
+	c := stubAPI(t, map[string]string{
+		"/item/1.json": `{"id":1,"type":"story"}`,
+		"/item/2.json": `null`,
+	})
+	ctx := context.Background()
+
+	body, err := c.Item(ctx, 1)
+	if err != nil || string(body) != `{"id":1,"type":"story"}` {
+		t.Errorf("Item(1) = %q, %v", body, err)
+	}
+	if _, err = c.Item(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Item(2) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	c := stubAPI(t, nil)
+	_, err := c.Item(context.Background(), 3)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Item(3) error = %v, want a status error", err)
+	}
+	if want := "GET /item/3.json: 404 Not Found"; err.Error() != want {
+		t.Errorf("Item(3) error = %q, want %q", err, want)
+	}
+	if _, err = c.Stories(context.Background(), AskFeed); err == nil {
+		t.Error("Stories(ask) succeeded on a 404")
+	}
+}
+
+func TestDecoding(t *testing.T) {
+	c := stubAPI(t, map[string]string{
+		"/topstories.json":  `[3,1,2]`,
+		"/showstories.json": `[5]`,
+		"/maxitem.json":     `42`,
+		"/updates.json":     `{"items":[8,9],"profiles":["alice"]}`,
+		"/user/alice.json":  `{"id":"alice","created":1500000000,"karma":7,"submitted":[8]}`,
+	})
+	ctx := context.Background()
+
+	if ids, err := c.TopStories(ctx); err != nil || !reflect.DeepEqual(ids, []int{3, 1, 2}) {
+		t.Errorf("TopStories() = %v, %v", ids, err)
+	}
+	if ids, err := c.Stories(ctx, ShowFeed); err != nil || !reflect.DeepEqual(ids, []int{5}) {
+		t.Errorf("Stories(show) = %v, %v", ids, err)
+	}
+	if id, err := c.MaxItem(ctx); err != nil || id != 42 {
+		t.Errorf("MaxItem() = %d, %v", id, err)
+	}
+	want := Updates{Items: []int{8, 9}, Profiles: []string{"alice"}}
+	if updates, err := c.Updates(ctx); err != nil || !reflect.DeepEqual(updates, want) {
+		t.Errorf("Updates() = %+v, %v", updates, err)
+	}
+	wantUser := User{Id: "alice", Created: 1500000000, Karma: 7, Submitted: []int{8}}
+	if user, err := c.User(ctx, "alice"); err != nil || !reflect.DeepEqual(user, wantUser) {
+		t.Errorf("User(alice) = %+v, %v", user, err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := New(srv.URL, DefaultTimeout).Item(ctx, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Item() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestParseFeed(t *testing.T) {
+	if feed, err := ParseFeed("Ask"); err != nil || feed != AskFeed {
+		t.Errorf("ParseFeed(Ask) = %q, %v", feed, err)
+	}
+	if _, err := ParseFeed("jobs"); err == nil || !strings.Contains(err.Error(), "unknown feed") {
+		t.Errorf("ParseFeed(jobs) error = %v", err)
+	}
+}
@@ -0,0 +1,335 @@
+package keys
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// All the keybindings of the app. They can be remapped by name (e.g.:
+// "next_feed") from the config file. The help of each binding is generated
+// from its keys, so the help overlay always shows the real keys.
+type KeyMap struct {
+	// everywhere
+	Quit key.Binding
+	Help key.Binding
+	// navigation (list, tree and focus modes)
+	Up       key.Binding
+	Down     key.Binding
+	PageUp   key.Binding
+	PageDown key.Binding
+	Top      key.Binding
+	Bottom   key.Binding
+	Jump     key.Binding // 0-9 (can't be remapped)
+	// scrolling (focus mode and article reader)
+	HalfPageUp   key.Binding
+	HalfPageDown key.Binding
+	// list mode
+	Select       key.Binding
+	Back         key.Binding
+	Hide         key.Binding
+	OpenUrl      key.Binding
+	OpenItem     key.Binding
+	User         key.Binding
+	Search       key.Binding
+	Find         key.Binding
+	FindNext     key.Binding
+	FindPrev     key.Binding
+	Retry        key.Binding
+	NextFeed     key.Binding
+	PrevFeed     key.Binding
+	CollapseMain key.Binding
+	Focus        key.Binding
+	Tree         key.Binding
+	Killfile     key.Binding
+	ShowFiltered key.Binding
+	Sort         key.Binding
+	Links        key.Binding
+	Article      key.Binding // also flips from the article to its comments
+	Export       key.Binding
+	NextNew      key.Binding // next comment that is new since the last visit
+	Bookmark     key.Binding
+	Tag          key.Binding // edits the tags of a bookmark
+	LivePause    key.Binding // pauses/resumes the live updates
+	Refresh      key.Binding // fetches the selected list again
+	// killfile view
+	KillfileAdd    key.Binding
+	KillfileRemove key.Binding
+	// link picker
+	LinkCopy    key.Binding
+	LinkPreview key.Binding
+	// focus mode
+	FocusPrev key.Binding
+	FocusNext key.Binding
+	// tree mode (take precedence over the list mode ones)
+	TreeToggle   key.Binding
+	TreeExpand   key.Binding
+	TreeCollapse key.Binding
+}
+
+func binding(desc string, keys ...string) key.Binding {
+	return key.NewBinding(
+		key.WithKeys(keys...),
+		key.WithHelp(helpKey(keys), desc),
+	)
+}
+
+// Returns how the given keys are shown in the help.
+func helpKey(keys []string) string {
+	shown := make([]string, len(keys))
+	for i, k := range keys {
+		if k == " " {
+			k = "space"
+		}
+		shown[i] = k
+	}
+	return strings.Join(shown, "/")
+}
+
+func Default() KeyMap {
+	return KeyMap{
+		Quit:         binding("quit", "ctrl+c", "q"),
+		Help:         binding("toggle help", "?"),
+		Up:           binding("move up", "up", "k"),
+		Down:         binding("move down", "down", "j"),
+		PageUp:       binding("move 10 up", "pgup"),
+		PageDown:     binding("move 10 down", "pgdown"),
+		Top:          binding("go to first", "g", "home"),
+		Bottom:       binding("go to last", "G", "end", "alt+["),
+		Jump:         key.NewBinding(key.WithKeys("0", "1", "2", "3", "4", "5", "6", "7", "8", "9"), key.WithHelp("0-9", "go to index")),
+		HalfPageUp:   binding("half page up", "ctrl+u"),
+		HalfPageDown: binding("half page down", "ctrl+d"),
+		Select:       binding("go in", "enter", "right", "l"),
+		Back:         binding("go back", "esc", "left", "h"),
+		Hide:         binding("hide/unhide", " "),
+		OpenUrl:      binding("open story URL", "o"),
+		OpenItem:     binding("open post on HN", "O"),
+		User:         binding("author profile", "u"),
+		Search:       binding("search", "s"),
+		Find:         binding("find in thread", "/"),
+		FindNext:     binding("next match", "n"),
+		FindPrev:     binding("previous match", "N"),
+		Retry:        binding("retry failed", "R"),
+		NextFeed:     binding("next feed", "tab"),
+		PrevFeed:     binding("previous feed", "shift+tab"),
+		CollapseMain: binding("collapse story", "F"),
+		Focus:        binding("focus mode", "f"),
+		Tree:         binding("tree mode", "t"),
+		Killfile:     binding("killfile rules", "K"),
+		ShowFiltered: binding("show filtered", "H"),
+		Sort:         binding("cycle sort order", "S"),
+		Links:        binding("list links", "L"),
+		Article:      binding("read article", "a"),
+		Export:       binding("export thread", "E"),
+		NextNew:      binding("next new comment", "U"),
+		Bookmark:     binding("save/unsave post", "b"),
+		Tag:          binding("tag saved post", "T"),
+		LivePause:    binding("pause live updates", "P"),
+		Refresh:      binding("refresh list", "r"),
+		// killfile view
+		KillfileAdd:    binding("add rule", "a"),
+		KillfileRemove: binding("remove rule", "d", "delete"),
+		// link picker
+		LinkCopy:    binding("copy link", "y"),
+		LinkPreview: binding("toggle preview", "p"),
+		// focus mode
+		FocusPrev: binding("previous post", "["),
+		FocusNext: binding("next post", "]"),
+		// tree mode
+		TreeToggle:   binding("collapse/expand", "enter"),
+		TreeExpand:   binding("expand", "right", "l"),
+		TreeCollapse: binding("collapse/parent", "left", "h"),
+	}
+}
+
+// the names used in the config file
+func (k *KeyMap) named() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":            &k.Quit,
+		"help":            &k.Help,
+		"up":              &k.Up,
+		"down":            &k.Down,
+		"page_up":         &k.PageUp,
+		"page_down":       &k.PageDown,
+		"top":             &k.Top,
+		"bottom":          &k.Bottom,
+		"jump":            &k.Jump,
+		"half_page_up":    &k.HalfPageUp,
+		"half_page_down":  &k.HalfPageDown,
+		"select":          &k.Select,
+		"back":            &k.Back,
+		"hide":            &k.Hide,
+		"open_url":        &k.OpenUrl,
+		"open_item":       &k.OpenItem,
+		"user":            &k.User,
+		"search":          &k.Search,
+		"find":            &k.Find,
+		"find_next":       &k.FindNext,
+		"find_prev":       &k.FindPrev,
+		"retry":           &k.Retry,
+		"next_feed":       &k.NextFeed,
+		"prev_feed":       &k.PrevFeed,
+		"collapse_main":   &k.CollapseMain,
+		"focus":           &k.Focus,
+		"tree":            &k.Tree,
+		"killfile":        &k.Killfile,
+		"show_filtered":   &k.ShowFiltered,
+		"sort":            &k.Sort,
+		"links":           &k.Links,
+		"article":         &k.Article,
+		"export":          &k.Export,
+		"next_new":        &k.NextNew,
+		"bookmark":        &k.Bookmark,
+		"tag":             &k.Tag,
+		"live_pause":      &k.LivePause,
+		"refresh":         &k.Refresh,
+		"link_copy":       &k.LinkCopy,
+		"link_preview":    &k.LinkPreview,
+		"killfile_add":    &k.KillfileAdd,
+		"killfile_remove": &k.KillfileRemove,
+		"focus_prev":      &k.FocusPrev,
+		"focus_next":      &k.FocusNext,
+		"tree_toggle":     &k.TreeToggle,
+		"tree_expand":     &k.TreeExpand,
+		"tree_collapse":   &k.TreeCollapse,
+	}
+}
+
+// The bindings active at the same time (a key can only do one thing in each).
+var modes = []struct {
+	name     string
+	bindings []string
+}{
+	{"list", []string{"quit", "help", "up", "down", "page_up", "page_down", "top", "bottom", "jump",
+		"select", "back", "hide", "open_url", "open_item", "user", "search", "find", "find_next",
+		"find_prev", "retry", "next_feed", "prev_feed", "collapse_main", "focus", "tree", "killfile",
+		"show_filtered", "sort", "links", "article", "export", "next_new", "bookmark", "tag", "live_pause",
+		"refresh"}},
+	{"tree", []string{"quit", "help", "up", "down", "page_up", "page_down", "top", "bottom", "jump",
+		"hide", "open_url", "open_item", "user", "search", "find", "find_next", "find_prev", "retry",
+		"next_feed", "prev_feed", "collapse_main", "focus", "tree", "killfile", "show_filtered",
+		"sort", "links", "article", "export", "next_new", "bookmark", "tag", "live_pause", "refresh",
+		"tree_toggle", "tree_expand", "tree_collapse"}},
+	{"focus", []string{"quit", "help", "up", "down", "page_up", "page_down", "half_page_up",
+		"half_page_down", "top", "bottom", "back", "focus", "links", "focus_prev", "focus_next"}},
+	{"killfile", []string{"quit", "up", "down", "back", "killfile", "killfile_add", "killfile_remove"}},
+	{"links", []string{"quit", "up", "down", "top", "bottom", "jump", "select", "back", "open_url",
+		"links", "link_copy", "link_preview"}},
+	{"reader", []string{"quit", "help", "up", "down", "page_up", "page_down", "half_page_up",
+		"half_page_down", "top", "bottom", "back", "open_url", "retry", "article"}},
+}
+
+// New creates the default keymap with the given bindings remapped (binding
+// name => keys). An empty list of keys unbinds the binding.
+func New(overrides map[string][]string) (KeyMap, error) {
+	k := Default()
+	named := k.named()
+
+	// sorted => the same config always gives the same error
+	var names []string
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b, exists := named[name]
+		if !exists {
+			return k, fmt.Errorf("unknown keybinding %q", name)
+		}
+		if name == "jump" {
+			return k, fmt.Errorf("keybinding %q can't be remapped", name)
+		}
+		keys := overrides[name]
+		if len(keys) == 0 {
+			b.Unbind()
+			continue
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(helpKey(keys), b.Help().Desc)
+	}
+
+	// conflicts
+	for _, mode := range modes {
+		boundTo := make(map[string]string)
+		for _, name := range mode.bindings {
+			for _, keyStr := range named[name].Keys() {
+				if other, taken := boundTo[keyStr]; taken {
+					return k, fmt.Errorf("key %q is bound to both %q and %q (in %s mode)", keyStr, other, name, mode.name)
+				}
+				boundTo[keyStr] = name
+			}
+		}
+	}
+	return k, nil
+}
+
+// FullHelp lists the bindings in columns, for the help overlay.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return bound([][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom, k.Jump, k.Select, k.Back},
+		{k.Hide, k.OpenUrl, k.OpenItem, k.User, k.Focus, k.CollapseMain, k.Tree, k.Killfile,
+			k.ShowFiltered, k.Sort, k.Links, k.Article, k.Export, k.Bookmark, k.Tag, k.Retry},
+		{k.NextFeed, k.PrevFeed, k.Search, k.Find, k.FindNext, k.FindPrev, k.NextNew, k.Refresh, k.LivePause,
+			k.Help, k.Quit},
+	})
+}
+
+// TreeHelp lists the bindings only available in tree mode.
+func (k KeyMap) TreeHelp() [][]key.Binding {
+	return bound([][]key.Binding{{k.TreeToggle, k.TreeExpand, k.TreeCollapse}})
+}
+
+// KillfileHelp lists the bindings of the killfile view.
+func (k KeyMap) KillfileHelp() [][]key.Binding {
+	return bound([][]key.Binding{{k.KillfileAdd, k.KillfileRemove, k.Back}})
+}
+
+// LinksHelp lists the bindings of the link picker.
+func (k KeyMap) LinksHelp() [][]key.Binding {
+	return bound([][]key.Binding{
+		{withDesc(k.Select, "open link"), withDesc(k.OpenUrl, "open link"), k.LinkCopy, k.LinkPreview},
+		{withDesc(k.Jump, "go to link"), withDesc(k.Back, "close")},
+	})
+}
+
+// FocusHelp lists the bindings only available in focus mode (and the article
+// reader, for the scrolling ones).
+func (k KeyMap) FocusHelp() [][]key.Binding {
+	return bound([][]key.Binding{{k.HalfPageUp, k.HalfPageDown, k.FocusPrev, k.FocusNext}})
+}
+
+// ReaderHelp lists the bindings that do something else in the article reader.
+func (k KeyMap) ReaderHelp() [][]key.Binding {
+	return bound([][]key.Binding{
+		{withDesc(k.Article, "show comments"), withDesc(k.OpenUrl, "open in browser"), withDesc(k.Back, "close")},
+	})
+}
+
+// ShortHelp is the help of the most used bindings.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Help, k.Quit}
+}
+
+// Returns a copy of the binding with another description (for bindings that
+// do something else in some views).
+func withDesc(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+	return b
+}
+
+// Returns the given columns without the unbound bindings.
+func bound(columns [][]key.Binding) [][]key.Binding {
+	ret := make([][]key.Binding, 0, len(columns))
+	for _, column := range columns {
+		var kept []key.Binding
+		for _, b := range column {
+			if len(b.Keys()) > 0 {
+				kept = append(kept, b)
+			}
+		}
+		ret = append(ret, kept)
+	}
+	return ret
+}
@@ -0,0 +1,213 @@
+package killfile
+
+import (
+	"errors"
+	"fmt"
+	"hackerreader/files"
+	"hackerreader/posts"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A killfile is a text file with rules (one per line) that hide or dim posts.
+// Empty lines and lines starting with # are ignored (comments go on their own
+// line: a # can be part of a rule, e.g.: in a title regexp). Example:
+//
+//	hide author:someone
+//	dim domain:example.com
+//	# subdomains included: news.bbc.co.uk too
+//	hide domain:bbc.co.uk
+//	# a Go regexp
+//	hide title:(?i)\bcrypto
+//	# stories with less than 10 points
+//	hide score:10
+//	# job, story, comment, poll or pollopt
+//	dim type:job
+//
+// The action ("hide" or "dim") can be left out: "hide" is the default.
+
+const RuleSyntax = "[hide|dim] author:NAME|domain:DOMAIN|title:REGEXP|score:N|type:TYPE"
+
+var types = []string{"job", "story", "comment", "poll", "pollopt"}
+
+type Rule struct {
+	Action posts.Filter // posts.Dimmed or posts.Killed
+	Field  string       // author, domain, title, score or type
+	Value  string
+	title  *regexp.Regexp
+	score  int
+}
+
+func ParseRule(s string) (Rule, error) {
+	rule := Rule{Action: posts.Killed}
+	// the value is kept as written (e.g.: title regexps can have spaces)
+	matcher := strings.TrimSpace(s)
+	action, rest := matcher, ""
+	if end := strings.IndexFunc(matcher, unicode.IsSpace); end >= 0 {
+		action, rest = matcher[:end], strings.TrimSpace(matcher[end:])
+	}
+	switch action {
+	case "hide":
+		matcher = rest
+	case "dim":
+		rule.Action = posts.Dimmed
+		matcher = rest
+	}
+	if len(matcher) == 0 {
+		return rule, errors.New("empty rule")
+	}
+
+	sepI := strings.Index(matcher, ":")
+	if sepI < 0 {
+		return rule, fmt.Errorf("expected field:value in rule %q", s)
+	}
+	rule.Field = strings.ToLower(matcher[:sepI])
+	rule.Value = matcher[sepI+1:]
+	if len(rule.Value) == 0 {
+		return rule, fmt.Errorf("missing the value of %q", rule.Field)
+	}
+
+	var err error
+	switch rule.Field {
+	case "author", "domain":
+	case "title":
+		rule.title, err = regexp.Compile(rule.Value)
+		if err != nil {
+			return rule, fmt.Errorf("invalid title regexp: %w", err)
+		}
+	case "score":
+		rule.score, err = strconv.Atoi(rule.Value)
+		if err != nil {
+			return rule, fmt.Errorf("invalid score %q", rule.Value)
+		}
+	case "type":
+		rule.Value = strings.ToLower(rule.Value)
+		known := false
+		for _, t := range types {
+			known = known || t == rule.Value
+		}
+		if !known {
+			return rule, fmt.Errorf("unknown type %q (expected one of %s)", rule.Value, strings.Join(types, ", "))
+		}
+	default:
+		return rule, fmt.Errorf("unknown field %q (expected author, domain, title, score or type)", rule.Field)
+	}
+	return rule, nil
+}
+
+func (r Rule) String() string {
+	action := "hide"
+	if r.Action == posts.Dimmed {
+		action = "dim"
+	}
+	return action + " " + r.Field + ":" + r.Value
+}
+
+func (r Rule) Matches(st *posts.Post) bool {
+	switch r.Field {
+	case "author":
+		return strings.EqualFold(st.By, r.Value)
+	case "domain":
+		// the whole host or a parent domain of it (the post's Domain is only
+		// the last two labels: it can't tell bbc.co.uk from co.uk)
+		host, domain := strings.ToLower(st.Host()), strings.ToLower(r.Value)
+		return len(host) > 0 && (host == domain || strings.HasSuffix(host, "."+domain))
+	case "title":
+		return len(st.Title) > 0 && r.title.MatchString(st.Title)
+	case "score":
+		// comments have no score
+		return st.Storytype != "comment" && st.Score < r.score
+	case "type":
+		return st.Storytype == r.Value
+	}
+	return false
+}
+
+type Killfile struct {
+	path      string
+	lines     []string // the whole file (comments included), so saving keeps it
+	ruleLines []int    // line of each rule
+	Rules     []Rule
+}
+
+// DefaultPath is $XDG_CONFIG_HOME/hackerreader/killfile (or the OS
+// equivalent).
+func DefaultPath() (string, error) {
+	return files.ConfigPath("killfile")
+}
+
+// Load reads the killfile at path. A missing file is an empty killfile (it's
+// created when a rule is added).
+func Load(path string) (*Killfile, error) {
+	k := &Killfile{path: path}
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return k, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	k.lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	for i, line := range k.lines {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		k.Rules = append(k.Rules, rule)
+		k.ruleLines = append(k.ruleLines, i)
+	}
+	return k, nil
+}
+
+func (k *Killfile) Path() string {
+	return k.path
+}
+
+// Check returns what the rules say about the post: hiding wins over dimming.
+func (k *Killfile) Check(st *posts.Post) posts.Filter {
+	filter := posts.Unfiltered
+	if !st.IsLoaded() {
+		return filter
+	}
+	for _, rule := range k.Rules {
+		if rule.Matches(st) && rule.Action > filter {
+			filter = rule.Action
+		}
+	}
+	return filter
+}
+
+// Add appends the rule to the killfile (and saves it).
+func (k *Killfile) Add(rule Rule) error {
+	k.lines = append(k.lines, rule.String())
+	k.ruleLines = append(k.ruleLines, len(k.lines)-1)
+	k.Rules = append(k.Rules, rule)
+	return k.save()
+}
+
+// Remove deletes the i-th rule from the killfile (and saves it).
+func (k *Killfile) Remove(i int) error {
+	if i < 0 || i >= len(k.Rules) {
+		return nil
+	}
+	line := k.ruleLines[i]
+	k.lines = append(k.lines[:line], k.lines[line+1:]...)
+	k.Rules = append(k.Rules[:i], k.Rules[i+1:]...)
+	k.ruleLines = append(k.ruleLines[:i], k.ruleLines[i+1:]...)
+	for j := i; j < len(k.ruleLines); j++ {
+		k.ruleLines[j]--
+	}
+	return k.save()
+}
+
+func (k *Killfile) save() error {
+	return files.WriteAtomic(k.path, []byte(strings.Join(k.lines, "\n")+"\n"))
+}
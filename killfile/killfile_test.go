@@ -0,0 +1,94 @@
+package killfile
+
+import (
+	"hackerreader/posts"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "killfile")
+	data := "# comments go on their own line\n\nhide title:C #\ndim author:someone\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	k, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(k.Rules) != 2 || k.Rules[0].String() != "hide title:C #" || k.Rules[1].String() != "dim author:someone" {
+		t.Fatalf("Load() = %v", k.Rules)
+	}
+	if f := k.Check(&posts.Post{Id: 1, Title: "Learning C # today"}); f != posts.Killed {
+		t.Errorf("Check() = %v, want Killed", f)
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "killfile")
+	k, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"title:(?i)#\\d+", "dim domain:example.com"} {
+		rule, err := ParseRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = k.Add(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = k.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// saved as it's shown
+	k, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(k.Rules) != 1 || k.Rules[0].String() != "hide title:(?i)#\\d+" {
+		t.Errorf("Load() after Add/Remove = %v", k.Rules)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	for _, s := range []string{"", "hide", "author", "title:(", "score:ten", "type:link", "points:10"} {
+		if _, err := ParseRule(s); err == nil {
+			t.Errorf("ParseRule(%q) succeeded", s)
+		}
+	}
+}
+
+func TestParseRuleKeepsValue(t *testing.T) {
+	for s, want := range map[string]string{
+		"title:foo\t bar":  "hide title:foo\t bar",
+		"dim\ttitle:a  b":  "dim title:a  b",
+		" hide  author:x ": "hide author:x",
+	} {
+		rule, err := ParseRule(s)
+		if err != nil || rule.String() != want {
+			t.Errorf("ParseRule(%q) = %q (%v), want %q", s, rule.String(), err, want)
+		}
+	}
+}
+
+func TestDomainRule(t *testing.T) {
+	rule, err := ParseRule("domain:BBC.co.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for url, want := range map[string]bool{
+		"https://bbc.co.uk/a":        true,
+		"https://www.bbc.co.uk/news": true,
+		"https://notbbc.co.uk/":      false,
+		"https://example.co.uk/":     false,
+		"":                           false,
+	} {
+		if got := rule.Matches(&posts.Post{Id: 1, Url: url}); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hackerreader/article"
+	"hackerreader/posts"
+	"hackerreader/style"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The link picker lists the links of the posts on screen (the selected post
+// and the hovered one), numbered like in their text.
+
+type linkEntry struct {
+	stId  int
+	label string // "url" or the number the link has in the text
+	link  posts.Link
+}
+
+// What a page says about itself (for the previews).
+type linkPreview struct {
+	title       string
+	description string
+	err         error
+}
+
+type linkPicker struct {
+	open     bool
+	entries  []linkEntry
+	cursor   int
+	preview  bool                    // show the preview of the link under the cursor
+	previews map[string]*linkPreview // by URL (nil while loading)
+	status   string                  // result of the last action (e.g.: copied)
+	number   string                  // the digits typed in a row (see Jump)
+}
+
+type linkPreviewMsg struct {
+	url     string
+	preview linkPreview
+}
+
+// Returns the id of the HN item the URL points at (if it does). Relative URLs
+// are relative to HN.
+func hnItemId(link string) (int, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return 0, false
+	}
+	if len(u.Host) > 0 && u.Host != "news.ycombinator.com" {
+		return 0, false
+	}
+	if strings.TrimPrefix(u.Path, "/") != "item" {
+		return 0, false
+	}
+	stId, err := strconv.Atoi(u.Query().Get("id"))
+	if err != nil || stId <= 0 {
+		return 0, false
+	}
+	return stId, true
+}
+
+// Makes relative URLs (e.g.: in user profiles) absolute
+func absoluteUrl(link string) string {
+	if u, err := url.Parse(link); err == nil && len(u.Host) == 0 {
+		return "https://news.ycombinator.com/" + strings.TrimPrefix(link, "/")
+	}
+	return link
+}
+
+// The posts whose links are listed
+func (m *model) linkSources() []int {
+	if m.inFocus > 0 {
+		return []int{m.inFocus}
+	}
+	var stIds []int
+	if parentId := m.selected.Peek().(int); parentId != rootStoryId && !m.getPost(parentId).IsPseudo() {
+		stIds = append(stIds, parentId)
+	}
+	if stId, ok := m.hoveredId(); ok {
+		stIds = append(stIds, stId)
+	}
+	return stIds
+}
+
+func (m *model) openLinks() {
+	m.links.entries = nil
+	for _, stId := range m.linkSources() {
+		st := m.getPost(stId)
+		if !st.IsLoaded() {
+			continue
+		}
+		for i, link := range st.AllLinks() {
+			label := strconv.Itoa(i)
+			if !st.HasUrl() {
+				label = strconv.Itoa(i + 1)
+			} else if i == 0 {
+				label = "url"
+			}
+			m.links.entries = append(m.links.entries, linkEntry{stId: stId, label: label, link: link})
+		}
+	}
+	m.links.open = true
+	m.links.cursor = 0
+	m.links.status = ""
+	m.links.number = ""
+}
+
+// Whether a link's label starts with the number.
+func (m *model) hasLinkLabel(number string) bool {
+	for _, entry := range m.links.entries {
+		if strings.HasPrefix(entry.label, number) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *model) linksKeyHandler(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.links.status = ""
+	typed := m.links.number
+	m.links.number = ""
+	switch {
+	case msg.Type == tea.KeyCtrlC:
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Back), key.Matches(msg, m.keys.Links), key.Matches(msg, m.keys.Quit):
+		m.links.open = false
+	case key.Matches(msg, m.keys.Down):
+		m.links.cursor = min(m.links.cursor+1, max(0, len(m.links.entries)-1))
+	case key.Matches(msg, m.keys.Up):
+		m.links.cursor = max(m.links.cursor-1, 0)
+	case key.Matches(msg, m.keys.Top):
+		m.links.cursor = 0
+	case key.Matches(msg, m.keys.Bottom):
+		m.links.cursor = max(0, len(m.links.entries)-1)
+	case key.Matches(msg, m.keys.Jump):
+		// the link with that number (the hovered post's if both posts have
+		// one): digits typed in a row add up while some label starts with them
+		number := typed + msg.String()
+		if !m.hasLinkLabel(number) {
+			number = msg.String()
+		}
+		m.links.number = number
+		for i, entry := range m.links.entries {
+			if entry.label == number {
+				m.links.cursor = i
+			}
+		}
+		return m, m.fetchPreview()
+	case key.Matches(msg, m.keys.Select), key.Matches(msg, m.keys.OpenUrl):
+		if entry, ok := m.hoveredLink(); ok {
+			m.links.open = false
+			m.followLink(entry.link.Url)
+		}
+	case key.Matches(msg, m.keys.LinkCopy):
+		if entry, ok := m.hoveredLink(); ok {
+			if err := clipboard.WriteAll(entry.link.Url); err != nil {
+				m.links.status = fmt.Sprintf("Couldn't copy the link: %s", err)
+			} else {
+				m.links.status = "Copied " + entry.link.Url
+			}
+		}
+	case key.Matches(msg, m.keys.LinkPreview):
+		m.links.preview = !m.links.preview
+	}
+	return m, m.fetchPreview()
+}
+
+func (m *model) hoveredLink() (linkEntry, bool) {
+	if m.links.cursor >= len(m.links.entries) {
+		return linkEntry{}, false
+	}
+	return m.links.entries[m.links.cursor], true
+}
+
+// Opens the link: HN items are shown by the reader itself, everything else
+// goes to the browser.
+func (m *model) followLink(link string) {
+	stId, isItem := hnItemId(link)
+	if !isItem {
+		m.openURL(absoluteUrl(link))
+		return
+	}
+
+	m.inFocus = -1
+	m.treeMode = false
+	m.collapseMain = false
+	m.prevCursor.Push(m.cursor)
+	m.selected.Push(stId)
+	m.cursor = 0
+	m.cancelLoads()
+	m.moveCursor(m.cursor) // loads the item (and its kids)
+}
+
+// Fetches the preview of the link under the cursor (if it's being previewed
+// and hasn't been fetched yet). HN items don't need one: they're shown as posts.
+func (m *model) fetchPreview() tea.Cmd {
+	entry, ok := m.hoveredLink()
+	if !m.links.preview || !ok || m.offline {
+		return nil
+	}
+	link := entry.link.Url
+	if _, isItem := hnItemId(link); isItem {
+		return nil
+	}
+	if _, fetched := m.links.previews[link]; fetched {
+		return nil
+	}
+	m.links.previews[link] = nil // loading
+
+	fetcher := m.fetcher
+	return func() tea.Msg {
+		return linkPreviewMsg{url: link, preview: fetchLinkPreview(context.Background(), fetcher, absoluteUrl(link))}
+	}
+}
+
+// Gets the page with the article reader's fetcher (same timeout and size
+// limit).
+func fetchLinkPreview(ctx context.Context, fetcher article.Fetcher, link string) linkPreview {
+	page, err := fetcher.Fetch(ctx, link)
+	if err != nil {
+		return linkPreview{err: err}
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return linkPreview{err: err}
+	}
+	preview := linkPreview{title: strings.TrimSpace(doc.Find("title").First().Text())}
+	for _, selector := range []string{`meta[name="description"]`, `meta[property="og:description"]`} {
+		if description, exists := doc.Find(selector).Attr("content"); exists {
+			preview.description = strings.TrimSpace(description)
+			break
+		}
+	}
+	return preview
+}
+
+func (m *model) setLinkPreview(msg linkPreviewMsg) {
+	preview := msg.preview
+	m.links.previews[msg.url] = &preview
+}
+
+func (m *model) linkPreviewView(entry linkEntry, w int) string {
+	if stId, isItem := hnItemId(entry.link.Url); isItem {
+		return m.getPost(stId).View(false, false, w, m.stories)
+	}
+	if m.offline {
+		return style.SecondaryStyle.Render("No previews while offline")
+	}
+	preview := m.links.previews[entry.link.Url]
+	if preview == nil {
+		return lipgloss.JoinHorizontal(lipgloss.Top,
+			m.spinner.View(), " ", style.SecondaryStyle.Render("Loading the preview..."))
+	}
+	if preview.err != nil {
+		return style.ErrorStyle.Copy().Width(w).Render(fmt.Sprintf("Couldn't load the preview: %s", preview.err))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left,
+		style.PrimaryStyle.Copy().Bold(true).Width(w).Render(preview.title),
+		style.PrimaryStyle.Copy().Width(w).Render(preview.description),
+	)
+}
+
+func (m *model) linksView() string {
+	w := m.cappedW - 4
+	title := style.PrimaryStyle.Copy().Bold(true)
+	rows := []string{title.Render("Links"), ""}
+	if len(m.links.entries) == 0 {
+		rows = append(rows, style.SecondaryStyle.Render("No links here"))
+	}
+	lastStId := 0
+	for i, entry := range m.links.entries {
+		if entry.stId != lastStId {
+			// a header per post
+			lastStId = entry.stId
+			st := m.getPost(entry.stId)
+			header := st.Title
+			if len(header) == 0 {
+				header = st.By + " " + st.TimeStr
+			}
+			if i > 0 {
+				rows = append(rows, "")
+			}
+			rows = append(rows, style.SecondaryStyle.Copy().MaxWidth(w).Render(header))
+		}
+
+		hovered := i == m.links.cursor
+		cursor := " "
+		if hovered {
+			cursor = style.Checkmark(">")
+		}
+		label := style.PrimaryStyle.Copy().Bold(hovered).Render(fmt.Sprintf("[%s] ", entry.label))
+		text := entry.link.Text
+		if len(text) == 0 || text == entry.link.Url {
+			text = entry.link.Url
+		} else {
+			text += " " + style.SecondaryStyle.Render(entry.link.Url)
+		}
+		rows = append(rows, lipgloss.NewStyle().MaxWidth(w).Render(lipgloss.JoinHorizontal(lipgloss.Top,
+			cursor, " ", label, style.PrimaryStyle.Copy().Bold(hovered).Render(text),
+		)))
+	}
+
+	if entry, ok := m.hoveredLink(); ok && m.links.preview {
+		rows = append(rows, "", title.Render("Preview"), m.linkPreviewView(entry, w))
+	}
+	if len(m.links.status) > 0 {
+		rows = append(rows, "", style.SecondaryStyle.Copy().MaxWidth(w).Render(m.links.status))
+	}
+
+	h := help.New()
+	h.Width = w
+	h.Styles.FullKey = style.PrimaryStyle.Copy().Bold(true)
+	h.Styles.FullDesc = style.SecondaryStyle
+	rows = append(rows, "", h.FullHelpView(m.keys.LinksHelp()))
+	return style.MainItem.
+		Width(m.cappedW - 2).
+		Render(lipgloss.JoinHorizontal(lipgloss.Top, " ", lipgloss.JoinVertical(lipgloss.Left, rows...)))
+}
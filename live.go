@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hackerreader/posts"
+	"hackerreader/thread"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Live updates: every now and then, the items HN changed recently
+// (/v0/updates.json) are fetched again if we have them. Their new replies are
+// appended to their kids (so nothing moves under the cursor), and the rows
+// that changed are marked until the cursor goes over them. The replies that
+// were posted during the session (newer than /v0/maxitem.json when it
+// started) are new comments.
+
+type live struct {
+	paused bool
+	gen    int   // bumped to stop the running poll loop (e.g.: when paused)
+	since  int   // the newest item when the session started (0 => no poll yet)
+	err    error // the last failed poll (while it's the error shown)
+}
+
+type pollMsg struct {
+	gen int
+}
+
+type updatesMsg struct {
+	gen     int
+	items   []int // the items that changed
+	maxItem int
+	err     error
+}
+
+type refreshedMsg struct {
+	gen   int
+	posts []posts.Post
+	err   error
+}
+
+func (m *model) livePolling() bool {
+	return m.cfg.Live.Enabled && !m.offline
+}
+
+// Waits for the next poll.
+func (m *model) schedulePoll() tea.Cmd {
+	if !m.livePolling() || m.live.paused {
+		return nil
+	}
+	gen := m.live.gen
+	return tea.Tick(m.cfg.Live.Interval, func(time.Time) tea.Msg {
+		return pollMsg{gen: gen}
+	})
+}
+
+func (m *model) fetchUpdates() tea.Cmd {
+	if !m.livePolling() {
+		return nil
+	}
+	gen := m.live.gen
+	return func() tea.Msg {
+		ctx := context.Background()
+		maxItem, err := m.client.MaxItem(ctx)
+		if err != nil {
+			return updatesMsg{gen: gen, err: err}
+		}
+		updates, err := m.client.Updates(ctx)
+		return updatesMsg{gen: gen, items: updates.Items, maxItem: maxItem, err: err}
+	}
+}
+
+// Loads items from the API, bypassing the cache (which is updated).
+func (m *model) freshLoader() thread.Load {
+	return func(ctx context.Context, id int) ([]byte, error) {
+		bodyBytes, err := m.client.Item(ctx, id)
+		if err == nil {
+			_ = m.cache.PutItem(id, bodyBytes)
+		}
+		return bodyBytes, err
+	}
+}
+
+func (m *model) refreshItems(ids []int) tea.Cmd {
+	gen := m.live.gen
+	load := m.freshLoader()
+	limit := m.cfg.Loading.MaxFetches
+	return func() tea.Msg {
+		fresh, err := thread.Items(context.Background(), load, ids, limit)
+		return refreshedMsg{gen: gen, posts: fresh, err: err}
+	}
+}
+
+func (m *model) setUpdates(msg updatesMsg) tea.Cmd {
+	if msg.gen != m.live.gen {
+		// paused meanwhile
+		return nil
+	}
+	if msg.err != nil {
+		m.setLiveErr(msg.err)
+		return m.schedulePoll()
+	}
+	m.clearLiveErr()
+	if m.live.since == 0 {
+		m.live.since = msg.maxItem
+	}
+	var ids []int
+	for _, stId := range msg.items {
+		if st, exists := m.stories[stId]; exists && st.IsLoaded() {
+			ids = append(ids, stId)
+		}
+	}
+	if len(ids) == 0 {
+		return m.schedulePoll()
+	}
+	return m.refreshItems(ids)
+}
+
+func (m *model) setRefreshed(msg refreshedMsg) tea.Cmd {
+	if msg.gen != m.live.gen {
+		return nil
+	}
+	if msg.err != nil {
+		m.setLiveErr(msg.err)
+		return m.schedulePoll()
+	}
+	for _, fresh := range msg.posts {
+		m.refreshPost(fresh)
+	}
+	m.setFocusContent() // might be the post in focus
+	return m.schedulePoll()
+}
+
+// Replaces the post with its fresh copy (if anything changed). The kids keep
+// their order (sorted or not): the new ones go last.
+func (m *model) refreshPost(fresh posts.Post) {
+	old, exists := m.stories[fresh.Id]
+	if !exists || !old.IsLoaded() {
+		return
+	}
+	m.sampleTrend(&fresh)
+	native := m.nativeKids(old)
+	known := make(map[int]bool, len(native))
+	for _, kidId := range native {
+		known[kidId] = true
+	}
+	var added []int
+	for _, kidId := range fresh.Kids {
+		if !known[kidId] {
+			added = append(added, kidId)
+		}
+	}
+	if len(added) == 0 && fresh.Score == old.Score && fresh.Descendants == old.Descendants &&
+		fresh.Title == old.Title && fresh.Text == old.Text && fresh.Dead == old.Dead && fresh.Deleted == old.Deleted {
+		return
+	}
+
+	if list, isSorted := m.sorted[old.Id]; isSorted && sameSlice(list.sorted, old.Kids) {
+		list.native = append(native[:len(native):len(native)], added...)
+		list.sorted = append(old.Kids[:len(old.Kids):len(old.Kids)], added...)
+		fresh.Kids = list.sorted
+	} else {
+		fresh.Kids = append(native[:len(native):len(native)], added...)
+	}
+	for _, kidId := range added {
+		if kidId > m.live.since {
+			m.markArrival(kidId)
+		}
+	}
+	wasNew := old.IsNew
+	fresh.NewComments = old.NewComments // kept as it was for the story being read
+	st := m.setPost(fresh)
+	st.IsNew = st.IsNew || wasNew
+	st.Updated = true
+}
+
+// Marks the post as new: it just showed up in a list (e.g.: a reply the live
+// updates added). If it isn't loaded yet, it's marked when it arrives.
+func (m *model) markArrival(stId int) {
+	if st, exists := m.stories[stId]; exists && st.IsLoaded() {
+		st.IsNew = true
+		return
+	}
+	m.arrivals[stId] = true
+}
+
+func (m *model) applyArrival(st *posts.Post) {
+	if m.arrivals[st.Id] {
+		delete(m.arrivals, st.Id)
+		st.IsNew = true
+	}
+}
+
+// The rows the cursor was on (and the selected post) were seen: their marks
+// go away.
+func (m *model) clearUpdated() {
+	if st, exists := m.stories[m.selected.Peek().(int)]; exists {
+		st.Updated = false
+	}
+	if stId, ok := m.hoveredId(); ok {
+		m.getPost(stId).Updated = false
+	}
+}
+
+// Pauses/resumes the polling (resuming polls right away).
+func (m *model) toggleLive() tea.Cmd {
+	if !m.livePolling() {
+		m.notice = "Live updates are disabled"
+		return nil
+	}
+	m.live.paused = !m.live.paused
+	m.live.gen++
+	if m.live.paused {
+		m.notice = "Live updates paused"
+		return nil
+	}
+	m.notice = "Live updates resumed"
+	return m.fetchUpdates()
+}
+
+// Shows the failed poll, unless another failure is shown (its retry, if any,
+// stays: the polls go on by themselves anyway).
+func (m *model) setLiveErr(err error) {
+	shown := m.err == nil || m.err == m.live.err
+	m.live.err = fmt.Errorf("couldn't poll for updates: %w", err)
+	if shown {
+		m.err = m.live.err
+	}
+}
+
+func (m *model) clearLiveErr() {
+	if m.live.err != nil && m.err == m.live.err {
+		m.clearErr()
+	}
+	m.live.err = nil
+}
@@ -0,0 +1,56 @@
+package posts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// A Link found in the HTML of a post.
+type Link struct {
+	Url  string `json:"url"`
+	Text string `json:"text"` // what the post shows (HN cuts long URLs short)
+}
+
+// Numbers the links of the HTML text (a " [n]" after each of them, counting
+// from 1), so they can be told apart on screen, and returns them. It's done
+// before the text goes through html2md, as the markdown only has the links
+// glamour knows how to show.
+func numberLinks(v string) (string, []Link) {
+	if !strings.Contains(v, "href") {
+		return v, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(v))
+	if err != nil {
+		return v, nil
+	}
+
+	var links []Link
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		if len(strings.TrimSpace(href)) == 0 {
+			return
+		}
+		links = append(links, Link{Url: href, Text: strings.TrimSpace(a.Text())})
+		a.AfterHtml(fmt.Sprintf(" [%d]", len(links)))
+	})
+	if len(links) == 0 {
+		return v, nil
+	}
+	numbered, err := doc.Find("body").Html()
+	if err != nil {
+		return v, links
+	}
+	return numbered, links
+}
+
+// AllLinks returns the links of the post: its URL (if any) followed by the
+// ones in its text.
+func (st *Post) AllLinks() []Link {
+	var links []Link
+	if st.HasUrl() {
+		links = append(links, Link{Url: st.Url, Text: st.Title})
+	}
+	return append(links, st.Links...)
+}
@@ -4,20 +4,36 @@ import (
 	"fmt"
 	html2md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/buger/jsonparser"
-	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"hackerreader/hn"
 	mySpinner "hackerreader/spinner"
 	"hackerreader/style"
 	"html"
+	"strconv"
 	"strings"
 )
 
 const (
-	loadingId = -1
+	loadingId     = -1
+	firstPseudoId = -2
+	// ItemUrl is where items live on the HN website (followed by the id)
+	ItemUrl = "https://news.ycombinator.com/item?id="
+	// UserUrl is where user profiles live on the HN website (followed by the name)
+	UserUrl = "https://news.ycombinator.com/user?id="
+)
+
+// What the killfile does with a post.
+type Filter int
+
+const (
+	Unfiltered Filter = iota
+	Dimmed            // shown, but faded
+	Killed            // shown as a single line (like hidden posts)
 )
 
 var (
 	html2mdConverter = html2md.NewConverter("", true, nil)
+	nextPseudoId     = firstPseudoId
 )
 
 type Post struct {
@@ -38,9 +54,24 @@ type Post struct {
 	Dead        bool   // true if the item is dead.
 	Deleted     bool   // true, if the item is deleted.
 	//
-	Hidden  bool   // whether the story has been hidden or not
-	TimeStr string // time in cool string format
-	Domain  string // the URL's domain
+	Hidden   bool   // whether the story has been hidden or not
+	Missing  bool   // not cached and can't be fetched (offline mode)
+	Stale    bool   // shown from a snapshot until it's fetched (see FromSnapshot)
+	Err      error  // why the post couldn't be loaded (if it couldn't)
+	Failures int    // how many times loading the post failed
+	Match    string // search term highlighted in the post ("" => none)
+	Filter   Filter // set from the killfile rules when the post arrives
+	TimeStr  string // time in cool string format
+	Domain   string // the URL's domain
+	Links    []Link // the links in the text (numbered in it)
+	//
+	Visited     bool // the story was read before (see the visits package)
+	NewComments int  // comments of the story since it was last read
+	IsNew       bool // comment that appeared since its story was last read
+	Updated     bool // changed by a live update (until the cursor goes over it)
+	//
+	Saved bool     // bookmarked (see the bookmarks package)
+	Tags  []string // the bookmark's tags
 	//
 	spinner *mySpinner.Spinner
 }
@@ -53,6 +84,35 @@ func New(spinner *mySpinner.Spinner) Post {
 	}
 }
 
+// Pseudo posts aren't HN items. They are made up by the reader to list other
+// items (e.g.: search results) on the same views as regular posts.
+// They get ids below loadingId, so they never collide with real items.
+func NewPseudo(storytype string, spinner *mySpinner.Spinner) Post {
+	data := New(spinner)
+	data.Id = nextPseudoId
+	data.Storytype = storytype
+	nextPseudoId--
+	return data
+}
+
+// HtmlToMd converts HN's HTML to the markdown the posts are rendered from.
+func HtmlToMd(v string) string {
+	// default to C-like syntax
+	v = strings.ReplaceAll(v, "<code>", "<code class=\"language-c\">")
+	text, err := html2mdConverter.ConvertString(v)
+	if err != nil {
+		// fallback
+		return html.UnescapeString(v)
+	}
+	// TODO wait for escape support to remove this
+	// TODO https://github.com/charmbracelet/glamour/issues/106
+	text = strings.ReplaceAll(text, "\\-", "-")
+	text = strings.ReplaceAll(text, "\\>", ">")
+	text = strings.ReplaceAll(text, "\\[", "[")
+	text = strings.ReplaceAll(text, "\\]", "]")
+	return text
+}
+
 func FromJSON(bytes []byte, spinner *mySpinner.Spinner) Post {
 	data := New(spinner)
 
@@ -93,20 +153,8 @@ func FromJSON(bytes []byte, spinner *mySpinner.Spinner) Post {
 			data.Title = v
 		case 5:
 			v, _ := jsonparser.ParseString(value)
-			// default to C-like syntax
-			v = strings.ReplaceAll(v, "<code>", "<code class=\"language-c\">")
-			data.Text, err = html2mdConverter.ConvertString(v)
-			if err != nil {
-				// fallback
-				data.Text = html.UnescapeString(v)
-			} else {
-				// TODO wait for escape support to remove this
-				// TODO https://github.com/charmbracelet/glamour/issues/106
-				data.Text = strings.ReplaceAll(data.Text, "\\-", "-")
-				data.Text = strings.ReplaceAll(data.Text, "\\>", ">")
-				data.Text = strings.ReplaceAll(data.Text, "\\[", "[")
-				data.Text = strings.ReplaceAll(data.Text, "\\]", "]")
-			}
+			v, data.Links = numberLinks(v)
+			data.Text = HtmlToMd(v)
 		case 6:
 			v, _ := jsonparser.ParseString(value)
 			data.Url = v
@@ -157,6 +205,11 @@ func (st *Post) HasUrl() bool {
 	return len(st.Url) > 0
 }
 
+// Host is the host name of the post's URL (Domain is only its last two labels).
+func (st *Post) Host() string {
+	return hostFromURL(st.Url)
+}
+
 func (st *Post) HasText() bool {
 	return len(st.Text) > 0
 }
@@ -165,6 +218,46 @@ func (st *Post) IsLoaded() bool {
 	return st.Id > 0
 }
 
+// Whether the post is waiting to be loaded (failed/missing posts aren't).
+func (st *Post) IsLoading() bool {
+	return st.Id == loadingId && st.Err == nil && !st.Missing
+}
+
+func (st *Post) IsPseudo() bool {
+	return st.Id < loadingId
+}
+
+// Permalink is the post's page on the HN website.
+func (st *Post) Permalink() string {
+	return ItemUrl + strconv.Itoa(st.Id)
+}
+
+// Fills a "user" pseudo post with the user's profile: the about text is the
+// post's text and the submitted items are its kids.
+func (st *Post) SetUser(user hn.User) {
+	st.By = user.Id
+	st.Title = user.Id
+	st.Time = user.Created
+	st.TimeStr = timestampToString(int64(user.Created))
+	st.Score = user.Karma
+	about, links := numberLinks(user.About)
+	st.Text = HtmlToMd(about)
+	st.Links = links
+	st.Kids = user.Submitted
+	st.Descendants = len(user.Submitted)
+}
+
+// Whether the text, title or author of the post contain query (ignoring case).
+func (st *Post) Matches(query string) bool {
+	if !st.IsLoaded() || len(query) == 0 {
+		return false
+	}
+	query = strings.ToLower(query)
+	return strings.Contains(strings.ToLower(st.Text), query) ||
+		strings.Contains(strings.ToLower(st.Title), query) ||
+		strings.Contains(strings.ToLower(st.By), query)
+}
+
 func (st *Post) ToggleHidden() {
 	st.Hidden = !st.Hidden
 }
@@ -184,6 +277,34 @@ func (st *Post) hiddenView(highlight bool, w int) string {
 		Render(fmt.Sprintf("(hidden) %s %s", st.By, st.TimeStr))
 }
 
+func (st *Post) filteredView(highlight bool, w int) string {
+	return style.SecondaryStyle.Copy().
+		Bold(highlight).
+		MaxWidth(w).
+		Render(fmt.Sprintf("(filtered) %s %s", st.By, st.TimeStr))
+}
+
+func (st *Post) missingView(highlight bool, w int) string {
+	return style.SecondaryStyle.Copy().
+		Bold(highlight).
+		MaxWidth(w).
+		Render("[not cached] unavailable offline")
+}
+
+func (st *Post) errorView(highlight bool, w int) string {
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		style.ErrorStyle.Copy().
+			Bold(highlight).
+			MaxWidth(w).
+			Render("[error] "+st.Err.Error()),
+		style.SecondaryStyle.Copy().
+			Bold(highlight).
+			MaxWidth(w).
+			Render(fmt.Sprintf("couldn't load post (%d failed attempts)", st.Failures)),
+	)
+}
+
 func (st *Post) loadingView(highlight bool, w int) string {
 	return lipgloss.JoinHorizontal(lipgloss.Top,
 		st.spinner.View(),
@@ -195,22 +316,119 @@ func (st *Post) loadingView(highlight bool, w int) string {
 	)
 }
 
+func (st *Post) textView(w int) string {
+	if !st.HasText() {
+		return ""
+	}
+	if text, cached := cachedText(st.Id, st.Text, w); cached {
+		return text
+	}
+	return plainText(st.Text, w)
+}
+
 func (st *Post) commentView(highlight bool, w int) string {
-	// -1 so wordwrap doesn't feel like ignoring the wrap
-	mdRenderer, _ := glamour.NewTermRenderer(
-		glamour.WithStyles(style.MdStyleConfig),
-		glamour.WithEmoji(),
-		glamour.WithWordWrap(w-1),
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		style.SecondaryStyle.Copy().
+			Bold(highlight).
+			MaxWidth(w).
+			Render(st.By+" "+st.TimeStr)+st.badgesView(highlight, " "),
+		st.textView(w),
 	)
-	commentTxt, _ := mdRenderer.Render(st.Text)
+}
 
-	return lipgloss.JoinVertical(
+// The badge of the comments that are new since the last visit
+func (st *Post) newView(highlight bool, sep string) string {
+	if !st.IsNew {
+		return ""
+	}
+	return style.SecondaryStyle.Copy().Bold(highlight).Render(sep) +
+		style.NewStyle.Copy().Bold(highlight).Render("new")
+}
+
+// The marks of the post (new, updated and saved), each one after sep
+func (st *Post) badgesView(highlight bool, sep string) string {
+	return st.newView(highlight, sep) + st.updatedView(highlight, sep) + st.savedView(highlight, sep)
+}
+
+// The mark of the posts a live update changed
+func (st *Post) updatedView(highlight bool, sep string) string {
+	if !st.Updated {
+		return ""
+	}
+	return style.SecondaryStyle.Copy().Bold(highlight).Render(sep) +
+		style.NewStyle.Copy().Bold(highlight).Render("updated")
+}
+
+// The bookmark's mark (and tags) of the saved posts, e.g.: "saved #go #rust"
+func (st *Post) savedView(highlight bool, sep string) string {
+	if !st.Saved {
+		return ""
+	}
+	saved := "saved"
+	for _, tag := range st.Tags {
+		saved += " #" + tag
+	}
+	return style.SecondaryStyle.Copy().Bold(highlight).Render(sep) +
+		style.PrimaryStyle.Copy().Bold(highlight).Render(saved)
+}
+
+// e.g.: "42 comments (3 new)" (the new ones only for stories already read)
+func (st *Post) commentsView(highlight bool) string {
+	comments := style.SecondaryStyle.Copy().Bold(highlight).Render(fmt.Sprintf("%d comments", st.Descendants))
+	switch {
+	case !st.Visited:
+		return comments
+	case st.NewComments > 0:
+		return comments + " " + style.NewStyle.Copy().Bold(highlight).Render(fmt.Sprintf("(%d new)", st.NewComments))
+	default:
+		return comments + " " + style.SecondaryStyle.Copy().Bold(highlight).Render("(read)")
+	}
+}
+
+func (st *Post) userView(highlight bool, selected bool, w int) string {
+	if st.Descendants < 0 {
+		// profile still loading
+		return lipgloss.JoinHorizontal(lipgloss.Top,
+			st.spinner.View(), " ", style.SecondaryStyle.Render("Loading "+st.Title+"..."))
+	}
+
+	row := lipgloss.JoinVertical(
 		lipgloss.Left,
+		style.PrimaryStyle.Copy().
+			Bold(highlight).
+			MaxWidth(w).
+			Render(st.Title),
 		style.SecondaryStyle.Copy().
 			Bold(highlight).
 			MaxWidth(w).
-			Render(st.By+" "+st.TimeStr),
-		strings.TrimRight(commentTxt, "\n"),
+			Render(fmt.Sprintf("%d karma | joined %s | %d submissions", st.Score, st.TimeStr, st.Descendants)),
+	)
+	if selected && st.HasText() {
+		row = lipgloss.JoinVertical(lipgloss.Left, row, st.textView(w))
+	}
+	return row
+}
+
+func (st *Post) searchView(highlight bool, w int) string {
+	var results string
+	if st.Descendants < 0 {
+		results = lipgloss.JoinHorizontal(lipgloss.Top,
+			st.spinner.View(), " ", style.SecondaryStyle.Render("Searching..."))
+	} else {
+		results = style.SecondaryStyle.Copy().
+			Bold(highlight).
+			MaxWidth(w).
+			Render(fmt.Sprintf("%d results", st.Descendants))
+	}
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		style.PrimaryStyle.Copy().
+			Bold(highlight).
+			Width(w).
+			Render("Search: "+st.Title),
+		results,
 	)
 }
 
@@ -229,6 +447,20 @@ func (st *Post) pollOptView(highlight bool, w int) string {
 }
 
 func (st *Post) View(highlight bool, selected bool, w int, stories map[int]*Post) string {
+	ret := st.view(highlight, selected, w, stories)
+	if st.Filter == Dimmed && !selected {
+		ret = style.SecondaryStyle.Copy().
+			Faint(true).
+			Bold(highlight).
+			Render(stripEscapes(ret))
+	}
+	if len(st.Match) > 0 {
+		ret = highlightMatches(ret, st.Match)
+	}
+	return ret
+}
+
+func (st *Post) view(highlight bool, selected bool, w int, stories map[int]*Post) string {
 	if st.Deleted || st.Dead {
 		// deleted story
 		return st.deletedView(highlight, w)
@@ -239,7 +471,22 @@ func (st *Post) View(highlight bool, selected bool, w int, stories map[int]*Post
 		return st.hiddenView(highlight, w)
 	}
 
-	if st.Id < 0 {
+	if st.Filter == Killed && !selected {
+		// filtered by the killfile
+		return st.filteredView(highlight, w)
+	}
+
+	if st.Missing {
+		// can't be loaded
+		return st.missingView(highlight, w)
+	}
+
+	if st.Err != nil {
+		// failed loading
+		return st.errorView(highlight, w)
+	}
+
+	if st.Id == loadingId {
 		// still loading/hasn't started loading
 		return st.loadingView(highlight, w)
 	}
@@ -249,6 +496,10 @@ func (st *Post) View(highlight bool, selected bool, w int, stories map[int]*Post
 		return st.commentView(highlight, w)
 	case "pollopt":
 		return st.pollOptView(highlight, w)
+	case "search":
+		return st.searchView(highlight, w)
+	case "user":
+		return st.userView(highlight, selected, w)
 	default:
 		// title should wrap if needed, but leave space for domain if possible
 		stTitleStyle := style.PrimaryStyle.Copy().Bold(highlight)
@@ -287,18 +538,7 @@ func (st *Post) View(highlight bool, selected bool, w int, stories map[int]*Post
 		if selected {
 			if len(st.Text) > 0 {
 				// story has text
-				mdRenderer, _ := glamour.NewTermRenderer(
-					glamour.WithStyles(style.MdStyleConfig),
-					glamour.WithEmoji(),
-					glamour.WithWordWrap(w-1),
-				)
-				storyTxt, _ := mdRenderer.Render(st.Text)
-
-				row = lipgloss.JoinVertical(
-					lipgloss.Left,
-					row,
-					strings.TrimRight(storyTxt, "\n"),
-				)
+				row = lipgloss.JoinVertical(lipgloss.Left, row, st.textView(w))
 			}
 
 			if st.Storytype == "poll" {
@@ -326,12 +566,12 @@ func (st *Post) View(highlight bool, selected bool, w int, stories map[int]*Post
 		row = lipgloss.JoinVertical(
 			lipgloss.Left,
 			row,
-			style.SecondaryStyle.Copy().
-				Bold(highlight).
-				MaxWidth(w).
-				Render(
-					fmt.Sprintf("%d points by %s %s | %d comments", st.Score, st.By, st.TimeStr, st.Descendants),
-				),
+			lipgloss.NewStyle().MaxWidth(w).Render(
+				style.SecondaryStyle.Copy().
+					Bold(highlight).
+					Render(fmt.Sprintf("%d points by %s %s | ", st.Score, st.By, st.TimeStr))+
+					st.commentsView(highlight)+st.badgesView(highlight, " | "),
+			),
 		)
 
 		return row
@@ -0,0 +1,180 @@
+package posts
+
+import (
+	"container/list"
+	"hackerreader/style"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Rendering markdown (glamour) is by far the slowest part of drawing a post,
+// so the rendered text of the posts is cached, and rendering happens outside
+// of the View: a post that isn't in the cache is shown as plain text and its
+// rendering is queued (see PendingRenders). Whoever runs the queued jobs
+// should draw the posts again when they are done.
+
+const (
+	renderCacheSize = 2000 // posts (at a given width)
+	RenderWorkers   = 4    // posts rendered at the same time
+	// renderers per width (+ 1 => renders outside of the workers, e.g.: from
+	// the UI's goroutine, don't wait for them)
+	maxRenderers = RenderWorkers + 1
+	maxPools     = 4 // widths (and themes) whose renderers are kept
+)
+
+type renderKey struct {
+	id    int
+	width int
+	theme string
+}
+
+type renderEntry struct {
+	key    renderKey
+	source string // the markdown it was rendered from (if the post changes, it's stale)
+	text   string
+}
+
+// A RenderJob is the markdown of a post waiting to be rendered.
+type RenderJob struct {
+	key    renderKey
+	source string
+}
+
+var (
+	renderMu    sync.Mutex
+	renderLRU   = list.New() // most recently used first
+	renderCache = make(map[renderKey]*list.Element)
+	pending     = make(map[renderKey]string) // key => source
+	inFlight    = make(map[renderKey]string)
+	running     int // jobs handed out by PendingRenders that aren't done yet
+
+	// glamour renderers are reused (they're not cheap to create), but can't
+	// render two things at once => a few of them per width (and theme)
+	poolsMu sync.Mutex
+	pools   = make(map[renderKey]*rendererPool)
+)
+
+type rendererPool struct {
+	free    chan *glamour.TermRenderer
+	created int
+}
+
+// RenderMarkdown renders the markdown (e.g.: the text of a post) for the
+// given width. It's slow: posts should go through the cache (Post.View).
+func RenderMarkdown(md string, w int) string {
+	pool, mdRenderer := getRenderer(w)
+	defer func() {
+		pool.free <- mdRenderer
+	}()
+	txt, _ := mdRenderer.Render(md)
+	return strings.TrimRight(txt, "\n")
+}
+
+// Returns a free renderer for the width, creating it if there are less than
+// maxRenderers (otherwise, it waits for one to be free).
+func getRenderer(w int) (*rendererPool, *glamour.TermRenderer) {
+	key := renderKey{width: w, theme: style.ThemeName}
+	poolsMu.Lock()
+	pool, exists := pools[key]
+	if !exists {
+		if len(pools) >= maxPools {
+			// e.g.: widths the terminal went through while resizing
+			for otherKey := range pools {
+				delete(pools, otherKey)
+				break
+			}
+		}
+		pool = &rendererPool{free: make(chan *glamour.TermRenderer, maxRenderers)}
+		pools[key] = pool
+	}
+	select {
+	case mdRenderer := <-pool.free:
+		poolsMu.Unlock()
+		return pool, mdRenderer
+	default:
+	}
+	if pool.created < maxRenderers {
+		pool.created++
+		poolsMu.Unlock()
+		// -1 so wordwrap doesn't feel like ignoring the wrap
+		mdRenderer, _ := glamour.NewTermRenderer(
+			glamour.WithStyles(style.MdStyleConfig),
+			glamour.WithEmoji(),
+			glamour.WithWordWrap(w-1),
+		)
+		return pool, mdRenderer
+	}
+	poolsMu.Unlock()
+	return pool, <-pool.free
+}
+
+// Returns the rendered text of the post (if it's cached and up to date).
+// Otherwise, its rendering is queued.
+func cachedText(id int, source string, w int) (string, bool) {
+	key := renderKey{id: id, width: w, theme: style.ThemeName}
+	renderMu.Lock()
+	defer renderMu.Unlock()
+	if elem, exists := renderCache[key]; exists {
+		entry := elem.Value.(*renderEntry)
+		if entry.source == source {
+			renderLRU.MoveToFront(elem)
+			return entry.text, true
+		}
+	}
+	if inFlight[key] != source {
+		pending[key] = source
+	}
+	return "", false
+}
+
+// PendingRenders returns the queued renders, as many as there are free
+// workers (at most RenderWorkers jobs run at the same time: the others wait
+// for a later call). Each of them should be Run (preferably outside of the
+// UI's goroutine), and PendingRenders called again when they're done.
+func PendingRenders() []RenderJob {
+	renderMu.Lock()
+	defer renderMu.Unlock()
+	var jobs []RenderJob
+	for key, source := range pending {
+		if running >= RenderWorkers {
+			break
+		}
+		jobs = append(jobs, RenderJob{key: key, source: source})
+		inFlight[key] = source
+		delete(pending, key)
+		running++
+	}
+	return jobs
+}
+
+// Run renders the markdown and caches the result.
+func (j RenderJob) Run() {
+	text := RenderMarkdown(j.source, j.key.width)
+
+	renderMu.Lock()
+	defer renderMu.Unlock()
+	running--
+	if inFlight[j.key] == j.source {
+		delete(inFlight, j.key)
+	}
+	entry := &renderEntry{key: j.key, source: j.source, text: text}
+	if elem, exists := renderCache[j.key]; exists {
+		elem.Value = entry
+		renderLRU.MoveToFront(elem)
+		return
+	}
+	renderCache[j.key] = renderLRU.PushFront(entry)
+	if renderLRU.Len() > renderCacheSize {
+		oldest := renderLRU.Back()
+		renderLRU.Remove(oldest)
+		delete(renderCache, oldest.Value.(*renderEntry).key)
+	}
+}
+
+// The text of the post while its markdown is being rendered
+func plainText(source string, w int) string {
+	return lipgloss.NewStyle().Width(w).Render(strings.TrimRight(source, "\n"))
+}
@@ -0,0 +1,52 @@
+package posts
+
+import (
+	"hackerreader/style"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestPendingRendersWorkers(t *testing.T) {
+	for id := 1; id <= 10; id++ {
+		if _, cached := cachedText(id, "post *"+strconv.Itoa(id)+"*", 40); cached {
+			t.Fatalf("post %d is already rendered", id)
+		}
+	}
+	jobs := PendingRenders()
+	if len(jobs) != RenderWorkers {
+		t.Fatalf("PendingRenders() = %d jobs, want %d", len(jobs), RenderWorkers)
+	}
+	if more := PendingRenders(); len(more) != 0 {
+		t.Fatalf("PendingRenders() = %d jobs while the workers are busy", len(more))
+	}
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job RenderJob) {
+			defer wg.Done()
+			job.Run()
+		}(job)
+	}
+	wg.Wait()
+	for _, job := range jobs {
+		if _, cached := cachedText(job.key.id, job.source, 40); !cached {
+			t.Errorf("post %d wasn't cached", job.key.id)
+		}
+	}
+
+	left := 0
+	for jobs = PendingRenders(); len(jobs) > 0; jobs = PendingRenders() {
+		left += len(jobs)
+		for _, job := range jobs {
+			job.Run()
+		}
+	}
+	if left != 10-RenderWorkers {
+		t.Errorf("%d renders were left, want %d", left, 10-RenderWorkers)
+	}
+	if pool := pools[renderKey{width: 40, theme: style.ThemeName}]; pool == nil || pool.created > maxRenderers {
+		t.Errorf("renderers created: %+v (at most %d)", pool, maxRenderers)
+	}
+}
@@ -0,0 +1,78 @@
+package posts
+
+import (
+	mySpinner "hackerreader/spinner"
+)
+
+// A Snapshot is a copy of a post's fields that can be kept (as JSON) after
+// the reader exits, e.g.: bookmarks. The text is the markdown the post is
+// shown from (with its links already numbered).
+type Snapshot struct {
+	Id          int    `json:"id"`
+	By          string `json:"by,omitempty"`
+	Time        int    `json:"time"`
+	Storytype   string `json:"type"`
+	Title       string `json:"title,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Url         string `json:"url,omitempty"`
+	Score       int    `json:"score,omitempty"`
+	Descendants int    `json:"descendants,omitempty"`
+	Kids        []int  `json:"kids,omitempty"`
+	Parts       []int  `json:"parts,omitempty"`
+	Poll        int    `json:"poll,omitempty"`
+	Parent      int    `json:"parent,omitempty"`
+	Dead        bool   `json:"dead,omitempty"`
+	Deleted     bool   `json:"deleted,omitempty"`
+	Links       []Link `json:"links,omitempty"`
+}
+
+// Snapshot copies the post, with its kids in the given order (Kids might be
+// sorted: the snapshot keeps HN's order).
+func (st *Post) Snapshot(kids []int) Snapshot {
+	return Snapshot{
+		Id:          st.Id,
+		By:          st.By,
+		Time:        st.Time,
+		Storytype:   st.Storytype,
+		Title:       st.Title,
+		Text:        st.Text,
+		Url:         st.Url,
+		Score:       st.Score,
+		Descendants: st.Descendants,
+		Kids:        kids,
+		Parts:       st.Parts,
+		Poll:        st.Poll,
+		Parent:      st.Parent,
+		Dead:        st.Dead,
+		Deleted:     st.Deleted,
+		Links:       st.Links,
+	}
+}
+
+// FromSnapshot turns the snapshot back into a loaded, but stale, post: it's
+// only shown until the post is fetched again.
+func FromSnapshot(s Snapshot, spinner *mySpinner.Spinner) Post {
+	data := New(spinner)
+	data.Id = s.Id
+	data.By = s.By
+	data.Time = s.Time
+	data.TimeStr = timestampToString(int64(s.Time))
+	data.Storytype = s.Storytype
+	data.Title = s.Title
+	data.Text = s.Text
+	data.Url = s.Url
+	if data.HasUrl() {
+		data.Domain = domainFromURL(s.Url)
+	}
+	data.Score = s.Score
+	data.Descendants = s.Descendants
+	data.Kids = s.Kids
+	data.Parts = s.Parts
+	data.Poll = s.Poll
+	data.Parent = s.Parent
+	data.Dead = s.Dead
+	data.Deleted = s.Deleted
+	data.Links = s.Links
+	data.Stale = true
+	return data
+}
@@ -5,6 +5,13 @@ import (
 	"net/url"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	highlightOn  = "\x1b[7m" // reverse video
+	highlightOff = "\x1b[27m"
 )
 
 func timestampToString(timestamp int64) string {
@@ -42,6 +49,15 @@ func timestampToString(timestamp int64) string {
 	return fmt.Sprintf("%d years ago", diff)
 }
 
+func hostFromURL(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		// not a URL => no host
+		return ""
+	}
+	return u.Hostname()
+}
+
 func domainFromURL(urlStr string) string {
 	u, err := url.Parse(urlStr)
 	if err != nil {
@@ -52,6 +68,95 @@ func domainFromURL(urlStr string) string {
 	return parts[len(parts)-2] + "." + parts[len(parts)-1]
 }
 
+// Returns the rendered text s with every occurrence of query (ignoring case) in
+// reverse video. Matches split between lines (word wrapping) aren't found.
+func highlightMatches(s string, query string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = highlightLine(line, []rune(strings.ToLower(query)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func highlightLine(line string, query []rune) string {
+	// the visible runes of the line (lowercase) and where they start/end in it
+	var runes []rune
+	var starts, ends []int
+	for i := 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		runes = append(runes, unicode.ToLower(r))
+		starts = append(starts, i)
+		ends = append(ends, i+size)
+		i += size
+	}
+
+	// byte ranges of the matches
+	var matches [][2]int
+	for i := 0; i+len(query) <= len(runes) && len(query) > 0; i++ {
+		if string(runes[i:i+len(query)]) == string(query) {
+			matches = append(matches, [2]int{starts[i], ends[i+len(query)-1]})
+			i += len(query) - 1
+		}
+	}
+	if len(matches) == 0 {
+		return line
+	}
+
+	var b strings.Builder
+	prev := 0
+	for _, match := range matches {
+		b.WriteString(line[prev:match[0]])
+		b.WriteString(highlightOn)
+		// styles inside the match can reset the highlight => set it again after them
+		for i := match[0]; i < match[1]; {
+			if n := escapeLen(line[i:]); n > 0 {
+				b.WriteString(line[i : i+n])
+				b.WriteString(highlightOn)
+				i += n
+				continue
+			}
+			b.WriteByte(line[i])
+			i++
+		}
+		b.WriteString(highlightOff)
+		prev = match[1]
+	}
+	b.WriteString(line[prev:])
+	return b.String()
+}
+
+// Returns s without its ANSI escape sequences (colors, bold, ...).
+func stripEscapes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// Returns the length of the ANSI escape sequence (CSI) at the start of s (0 if
+// there's none).
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
 func max(a int, b int) int {
 	if a > b {
 		return a
@@ -0,0 +1,86 @@
+package prompt
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"hackerreader/style"
+)
+
+// This is a wrapper around github.com/charmbracelet/bubbles textinput.
+// The cursor doesn't blink, so the input only changes on key presses (and
+//   the last frame can keep being reused in between).
+// It also keeps track of whether it is open and of the error (if any) of the
+//   last submitted value.
+
+type Prompt struct {
+	input textinput.Model
+	open  bool
+	err   error
+}
+
+func New() Prompt {
+	ret := Prompt{
+		input: textinput.New(),
+		open:  false,
+	}
+
+	ret.input.SetCursorMode(textinput.CursorStatic)
+	ret.input.PromptStyle = style.PrimaryStyle.Copy().Bold(true)
+	ret.input.PlaceholderStyle = style.SecondaryStyle
+
+	return ret
+}
+
+// Opens the prompt with the given prompt string and placeholder.
+func (this *Prompt) Open(prompt string, placeholder string) {
+	this.input.Prompt = prompt
+	this.input.Placeholder = placeholder
+	this.input.Reset()
+	this.input.Focus()
+	this.open = true
+	this.err = nil
+}
+
+// Sets the text being typed (e.g.: to edit a previous value).
+func (this *Prompt) SetValue(value string) {
+	this.input.SetValue(value)
+	this.input.CursorEnd()
+}
+
+func (this *Prompt) Close() {
+	this.input.Blur()
+	this.open = false
+	this.err = nil
+}
+
+func (this *Prompt) IsOpen() bool {
+	return this.open
+}
+
+func (this *Prompt) Value() string {
+	return this.input.Value()
+}
+
+// Shows an error under the input (e.g.: the submitted value is invalid).
+func (this *Prompt) SetError(err error) {
+	this.err = err
+}
+
+func (this *Prompt) Update(msg tea.KeyMsg) tea.Cmd {
+	this.err = nil // typing clears the error
+	var cmd tea.Cmd
+	this.input, cmd = this.input.Update(msg)
+	return cmd
+}
+
+func (this *Prompt) View(w int) string {
+	this.input.Width = w - lipgloss.Width(this.input.Prompt) - 1
+	if this.err == nil {
+		return this.input.View()
+	}
+	return lipgloss.JoinVertical(lipgloss.Left,
+		this.input.View(),
+		style.ErrorStyle.Copy().MaxWidth(w).Render(this.err.Error()),
+	)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hackerreader/article"
+	"hackerreader/posts"
+	"hackerreader/style"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The reader mode shows the article a story links to (instead of sending it
+// to the browser). Articles are kept for the session, so flipping between an
+// article and its comments doesn't fetch it again.
+
+type readerPage struct {
+	article  article.Article
+	markdown string
+	rendered string
+	width    int // what it was rendered for
+	err      error
+	loading  bool
+}
+
+type reader struct {
+	stId     int // story being read (0 => closed)
+	viewport viewport.Model
+	pages    map[int]*readerPage // by story id
+}
+
+type articleMsg struct {
+	stId     int
+	article  article.Article
+	markdown string
+	rendered string
+	width    int
+	err      error
+}
+
+func (m *model) readerWidth() int {
+	return m.cappedW - 2
+}
+
+func articleMarkdown(a article.Article) string {
+	md := "# " + a.Title + "\n\n"
+	if len(a.Byline) > 0 {
+		md += "*" + a.Byline + "*\n\n"
+	}
+	return md + a.Url + "\n\n" + posts.HtmlToMd(a.Content)
+}
+
+func (m *model) fetchArticle(stId int, url string, width int) tea.Cmd {
+	return func() tea.Msg {
+		a, err := article.Fetch(context.Background(), m.fetcher, url)
+		if err != nil {
+			return articleMsg{stId: stId, err: err}
+		}
+		md := articleMarkdown(a)
+		return articleMsg{
+			stId:     stId,
+			article:  a,
+			markdown: md,
+			rendered: posts.RenderMarkdown(md, width),
+			width:    width,
+		}
+	}
+}
+
+// Returns the story whose article `a` opens: the selected story or, if none
+// is, the hovered one.
+func (m *model) articleTarget() (int, bool) {
+	candidates := []int{m.selected.Peek().(int)}
+	if stId, ok := m.hoveredId(); ok {
+		candidates = append(candidates, stId)
+	}
+	for _, stId := range candidates {
+		st := m.getPost(stId)
+		if st.IsLoaded() && st.HasUrl() {
+			return stId, true
+		}
+	}
+	return 0, false
+}
+
+func (m *model) openArticle(stId int) tea.Cmd {
+	m.reader.stId = stId
+	m.reader.viewport = viewport.Model{Width: m.cappedW, Height: m.viewportHeight()}
+
+	page, exists := m.reader.pages[stId]
+	if exists && page.err == nil {
+		m.setReaderContent(page)
+		return nil
+	}
+	page = &readerPage{loading: true}
+	m.reader.pages[stId] = page
+	if m.offline {
+		page.loading = false
+		page.err = errors.New("articles can't be fetched while offline")
+		return nil
+	}
+	return m.fetchArticle(stId, m.getPost(stId).Url, m.readerWidth())
+}
+
+func (m *model) setArticle(msg articleMsg) {
+	page, exists := m.reader.pages[msg.stId]
+	if !exists {
+		return
+	}
+	page.loading = false
+	page.err = msg.err
+	page.article = msg.article
+	page.markdown = msg.markdown
+	page.rendered = msg.rendered
+	page.width = msg.width
+	if m.reader.stId == msg.stId {
+		m.setReaderContent(page)
+	}
+}
+
+// Puts the page in the viewport, rendering it again if the width changed.
+func (m *model) setReaderContent(page *readerPage) {
+	m.reader.viewport.Width = m.cappedW
+	m.reader.viewport.Height = m.viewportHeight()
+	if page.loading || page.err != nil {
+		return
+	}
+	if page.width != m.readerWidth() {
+		page.width = m.readerWidth()
+		page.rendered = posts.RenderMarkdown(page.markdown, page.width)
+	}
+	m.reader.viewport.SetContent(page.rendered)
+}
+
+func (m *model) closeArticle() {
+	m.reader.stId = 0
+}
+
+// Leaves the article for its comments (the story is selected if it isn't).
+func (m *model) articleToComments() {
+	stId := m.reader.stId
+	m.closeArticle()
+	if m.selected.Peek().(int) == stId {
+		return
+	}
+	// from a list => go in the story
+	if i := indexOf(m.getPost(m.selected.Peek().(int)).Kids, stId); i >= 0 {
+		m.cursor = i
+	}
+	m.inFocus = -1
+	m.treeMode = false
+	m.collapseMain = false
+	m.prevCursor.Push(m.cursor)
+	m.selected.Push(stId)
+	m.cursor = 0
+	m.cancelLoads()
+	m.moveCursor(m.cursor)
+}
+
+func (m *model) readerKeyHandler(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	vp := &m.reader.viewport
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Help):
+		m.showHelp = true
+	case key.Matches(msg, m.keys.Back):
+		m.closeArticle()
+	case key.Matches(msg, m.keys.Article):
+		m.articleToComments()
+	case key.Matches(msg, m.keys.OpenUrl):
+		m.openURL(m.getPost(m.reader.stId).Url)
+	case key.Matches(msg, m.keys.Retry):
+		if page := m.reader.pages[m.reader.stId]; page.err != nil {
+			return m, m.openArticle(m.reader.stId)
+		}
+	case key.Matches(msg, m.keys.Down):
+		vp.LineDown(1)
+	case key.Matches(msg, m.keys.Up):
+		vp.LineUp(1)
+	case key.Matches(msg, m.keys.PageDown):
+		vp.ViewDown()
+	case key.Matches(msg, m.keys.PageUp):
+		vp.ViewUp()
+	case key.Matches(msg, m.keys.HalfPageDown):
+		vp.HalfViewDown()
+	case key.Matches(msg, m.keys.HalfPageUp):
+		vp.HalfViewUp()
+	case key.Matches(msg, m.keys.Top):
+		vp.GotoTop()
+	case key.Matches(msg, m.keys.Bottom):
+		vp.GotoBottom()
+	}
+	return m, nil
+}
+
+func (m *model) readerView() string {
+	st := m.getPost(m.reader.stId)
+	page := m.reader.pages[m.reader.stId]
+
+	var body string
+	switch {
+	case page.loading:
+		body = lipgloss.JoinHorizontal(lipgloss.Top,
+			m.spinner.View(), " ", style.SecondaryStyle.Render("Loading "+st.Url+"..."))
+	case page.err != nil:
+		body = style.ErrorStyle.Copy().Width(m.cappedW).Render(fmt.Sprintf(
+			"Couldn't load the article: %s (%s to retry, %s to open it in the browser)",
+			page.err, m.keys.Retry.Help().Key, m.keys.OpenUrl.Help().Key))
+	default:
+		body = m.reader.viewport.View()
+	}
+	body = lipgloss.NewStyle().Height(m.viewportHeight()).MaxHeight(m.viewportHeight()).Render(body)
+
+	status := st.Title
+	if !page.loading && page.err == nil {
+		status = fmt.Sprintf("%3.f%% %s", scrollPercent(m.reader.viewport), status)
+	}
+	status += fmt.Sprintf(" (%s comments, %s browser, %s back)",
+		m.keys.Article.Help().Key, m.keys.OpenUrl.Help().Key, strings.Split(m.keys.Back.Help().Key, "/")[0])
+	return lipgloss.JoinVertical(lipgloss.Left,
+		body,
+		style.SecondaryStyle.Copy().MaxWidth(m.w).Render(status),
+	)
+}
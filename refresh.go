@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hackerreader/hn"
+	"hackerreader/posts"
+	"hackerreader/thread"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Refreshing the selected list by hand: the feed (or the selected post) is
+// fetched again, along with the posts of the list that were loaded. The
+// cursor stays on the same post wherever it went, the posts that left the
+// list are dropped and the ones that came are marked as new.
+
+type refreshMsg struct {
+	stId  int     // the refreshed list
+	feed  hn.Feed // the root list's feed (if stId is the root)
+	kids  []int   // the root list's stories
+	posts []posts.Post
+}
+
+func (m *model) fetchRefresh(stId int) tea.Cmd {
+	feed := m.feed
+	ids := []int{stId}
+	if stId == rootStoryId {
+		ids = nil
+	}
+	for _, kidId := range m.nativeKids(m.getPost(stId)) {
+		if kid, exists := m.stories[kidId]; exists && kid.IsLoaded() {
+			ids = append(ids, kidId)
+		}
+	}
+	load := m.freshLoader()
+	limit := m.cfg.Loading.MaxFetches
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		ctx := context.Background()
+		msg := refreshMsg{stId: stId, feed: feed}
+		if stId == rootStoryId && feed != savedFeed {
+			stories, err := m.client.Stories(ctx, feed)
+			if err != nil {
+				return errMsg{err: err, retry: cmd}
+			}
+			_ = m.cache.PutFeed(string(feed), stories)
+			msg.kids = stories
+		}
+		fresh, err := thread.Items(ctx, load, ids, limit)
+		if err != nil {
+			return errMsg{err: err, retry: cmd}
+		}
+		msg.posts = fresh
+		return msg
+	}
+	return cmd
+}
+
+// Refreshes the selected list (pseudo posts, e.g.: search results, can't be).
+func (m *model) refresh() tea.Cmd {
+	stId := m.selected.Peek().(int)
+	if m.offline || m.getPost(stId).IsPseudo() {
+		m.notice = "This list can't be refreshed"
+		return nil
+	}
+	return m.fetchRefresh(stId)
+}
+
+func (m *model) setRefresh(msg refreshMsg) {
+	var prevKids []int // in HN's order
+	hoveredId, hovering := m.listCursorId(msg.stId)
+	savedId, saved := 0, false // the post "back" returns to, if the user went in
+	m.updateSavedCursor(msg.stId, func(cursor int) int {
+		if kids := m.getPost(msg.stId).Kids; cursor < len(kids) {
+			savedId, saved = kids[cursor], true
+		}
+		return cursor
+	})
+	st := m.getPost(msg.stId)
+	switch {
+	case msg.stId != rootStoryId:
+		if !st.IsLoaded() || len(msg.posts) == 0 || msg.posts[0].Id != msg.stId {
+			// gone (e.g.: deleted items are "null")
+			return
+		}
+		prevKids = m.nativeKids(st)
+		fresh := msg.posts[0]
+		msg.posts = msg.posts[1:]
+		m.sampleTrend(&fresh)
+		fresh.NewComments = st.NewComments // kept as it was for the story being read
+		st = m.setPost(fresh)
+	case msg.feed == savedFeed:
+		if m.feed != savedFeed {
+			return
+		}
+		prevKids = m.nativeKids(st)
+		m.feeds[savedFeed] = m.savedStories()
+		st.Kids = m.feeds[savedFeed]
+	default:
+		m.feeds[msg.feed] = msg.kids
+		m.rankFeed(msg.feed, msg.kids)
+		if m.feed != msg.feed {
+			// switched feeds meanwhile: shown when switching back
+			return
+		}
+		prevKids = m.nativeKids(st)
+		st.Kids = msg.kids
+		st.Descendants = len(msg.kids)
+	}
+
+	came, left := m.reconcileKids(st, prevKids)
+	for _, fresh := range msg.posts {
+		m.refreshPost(fresh)
+	}
+	if hovering && m.selected.Peek().(int) == msg.stId {
+		if i := indexOf(st.Kids, hoveredId); i >= 0 {
+			m.cursor = i
+		}
+		m.moveCursor(m.cursor) // in bounds (and loads what's around)
+	}
+	if saved {
+		m.updateSavedCursor(msg.stId, func(cursor int) int {
+			if i := indexOf(st.Kids, savedId); i >= 0 {
+				return i
+			}
+			return cursor // put in bounds when going back
+		})
+	}
+	m.setFocusContent()
+	m.notice = fmt.Sprintf("Refreshed: %d new, %d gone", came, left)
+}
+
+// The post the list's cursor is on, if it's the selected list (in tree mode,
+// the tree's cursor is an id already: it's the list's once back).
+func (m *model) listCursorId(stId int) (int, bool) {
+	st := m.getPost(stId)
+	if m.selected.Peek().(int) != stId || m.cursor >= st.KidCount() {
+		return 0, false
+	}
+	return st.Kids[m.cursor], true
+}
+
+// Replaces the cursor saved for the list stId when the user went into one of
+// its posts (if they did) with what update returns. The stacks only show their
+// tops: they're unwound down to the list and rebuilt.
+func (m *model) updateSavedCursor(stId int, update func(cursor int) int) {
+	var ids, cursors []interface{}
+	for m.selected.Len() > 1 && m.selected.Peek().(int) != stId {
+		ids = append(ids, m.selected.Pop())
+		cursors = append(cursors, m.prevCursor.Pop())
+	}
+	if last := len(cursors) - 1; last >= 0 && m.selected.Peek().(int) == stId {
+		cursors[last] = update(cursors[last].(int))
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		m.selected.Push(ids[i])
+		m.prevCursor.Push(cursors[i])
+	}
+}
+
+// Marks the kids that weren't in the list before as new. Returns how many
+// came and left.
+func (m *model) reconcileKids(st *posts.Post, prevKids []int) (int, int) {
+	prev := make(map[int]bool, len(prevKids))
+	for _, kidId := range prevKids {
+		prev[kidId] = true
+	}
+	current := make(map[int]bool, len(st.Kids))
+	came := 0
+	for _, kidId := range st.Kids {
+		current[kidId] = true
+		if !prev[kidId] {
+			came++
+			m.markArrival(kidId)
+		}
+	}
+	left := 0
+	for _, kidId := range prevKids {
+		if !current[kidId] {
+			left++
+		}
+	}
+	return came, left
+}
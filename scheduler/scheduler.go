@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"context"
+)
+
+// The scheduler decides which items get fetched and when. It doesn't fetch
+// anything itself: it only hands out jobs (while there are free slots) and is
+// told when they are done. It isn't safe for concurrent use: it is meant to be
+// driven from the Update function.
+
+type Priority int
+
+const (
+	Background Priority = iota // prefetching
+	Near                       // close to the cursor
+	Visible                    // on screen right now
+)
+
+const DefaultLimit = 8
+
+type Job struct {
+	Id     int             // the item to fetch
+	Ticket int             // identifies this run of the job (see Done)
+	Ctx    context.Context // cancelled if the job is cancelled
+}
+
+type queued struct {
+	prio  Priority
+	order int // first come, first served (within the same priority)
+}
+
+type running struct {
+	ticket int
+	cancel context.CancelFunc
+}
+
+type Scheduler struct {
+	limit   int
+	queued  map[int]queued
+	running map[int]running
+	counter int
+}
+
+func New(limit int) *Scheduler {
+	return &Scheduler{
+		limit:   limit,
+		queued:  make(map[int]queued),
+		running: make(map[int]running),
+	}
+}
+
+// Queue asks for the item to be fetched. Queueing an item again can only
+// raise its priority. Items being fetched are left alone.
+func (s *Scheduler) Queue(id int, prio Priority) {
+	if _, isRunning := s.running[id]; isRunning {
+		return
+	}
+	q, isQueued := s.queued[id]
+	if isQueued {
+		if prio > q.prio {
+			q.prio = prio
+			s.queued[id] = q
+		}
+		return
+	}
+	s.counter++
+	s.queued[id] = queued{prio: prio, order: s.counter}
+}
+
+func (s *Scheduler) pop() (int, bool) {
+	bestId, found := 0, false
+	var best queued
+	for id, q := range s.queued {
+		if !found || q.prio > best.prio || (q.prio == best.prio && q.order < best.order) {
+			bestId, best, found = id, q, true
+		}
+	}
+	if found {
+		delete(s.queued, bestId)
+	}
+	return bestId, found
+}
+
+// Next starts as many jobs as there are free slots, highest priority first.
+func (s *Scheduler) Next() []Job {
+	var jobs []Job
+	for len(s.running) < s.limit {
+		id, ok := s.pop()
+		if !ok {
+			break
+		}
+		s.counter++
+		ctx, cancel := context.WithCancel(context.Background())
+		s.running[id] = running{ticket: s.counter, cancel: cancel}
+		jobs = append(jobs, Job{Id: id, Ticket: s.counter, Ctx: ctx})
+	}
+	return jobs
+}
+
+// Done frees the job's slot. Returns false if the job had been cancelled
+// (or replaced by a newer run of the same item), i.e.: its result is stale.
+func (s *Scheduler) Done(id int, ticket int) bool {
+	r, isRunning := s.running[id]
+	if !isRunning || r.ticket != ticket {
+		return false
+	}
+	r.cancel()
+	delete(s.running, id)
+	return true
+}
+
+// CancelUnless drops from the queue and cancels the running jobs of every
+// item for which keep returns false (e.g.: items we navigated away from).
+func (s *Scheduler) CancelUnless(keep func(id int) bool) {
+	for id := range s.queued {
+		if !keep(id) {
+			delete(s.queued, id)
+		}
+	}
+	for id, r := range s.running {
+		if !keep(id) {
+			r.cancel()
+			delete(s.running, id)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+)
+
+type queueOp struct {
+	id   int
+	prio Priority
+}
+
+func ids(jobs []Job) []int {
+	var ret []int
+	for _, job := range jobs {
+		ret = append(ret, job.Id)
+	}
+	return ret
+}
+
+func TestNextOrder(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		queue []queueOp
+		want  []int
+	}{
+		{
+			name:  "first come, first served",
+			queue: []queueOp{{1, Near}, {2, Near}, {3, Near}},
+			want:  []int{1, 2, 3},
+		},
+		{
+			name:  "highest priority first",
+			queue: []queueOp{{1, Background}, {2, Near}, {3, Visible}, {4, Near}},
+			want:  []int{3, 2, 4, 1},
+		},
+		{
+			name:  "queued again => promoted",
+			queue: []queueOp{{1, Background}, {2, Near}, {1, Visible}},
+			want:  []int{1, 2},
+		},
+		{
+			name:  "queued again lower => kept",
+			queue: []queueOp{{1, Visible}, {2, Near}, {1, Background}},
+			want:  []int{1, 2},
+		},
+		{
+			name:  "promoted keeps its place among its new peers",
+			queue: []queueOp{{1, Background}, {2, Visible}, {1, Visible}},
+			want:  []int{1, 2},
+		},
+	} {
+		s := New(DefaultLimit)
+		for _, op := range test.queue {
+			s.Queue(op.id, op.prio)
+		}
+		if got := ids(s.Next()); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Next() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	s := New(2)
+	for id := 1; id <= 3; id++ {
+		s.Queue(id, Near)
+	}
+	jobs := s.Next()
+	if got := ids(jobs); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Fatalf("Next() = %v, want [1 2]", got)
+	}
+	s.Queue(1, Visible) // running => left alone
+	if more := s.Next(); len(more) != 0 {
+		t.Fatalf("Next() = %v with every slot taken", ids(more))
+	}
+	if !s.Done(jobs[0].Id, jobs[0].Ticket) {
+		t.Fatal("Done() = false for a running job")
+	}
+	if got := ids(s.Next()); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("Next() after Done = %v, want [3]", got)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		keep    bool
+		requeue bool
+		done    bool // what Done says about the first run
+	}{
+		{name: "kept", keep: true, done: true},
+		{name: "cancelled", keep: false, done: false},
+		{name: "cancelled and run again", keep: false, requeue: true, done: false},
+	} {
+		s := New(DefaultLimit)
+		s.Queue(1, Visible)
+		s.Queue(2, Near) // queued, not running
+		first := s.Next()
+		s.CancelUnless(func(id int) bool { return test.keep })
+		if test.keep != (first[0].Ctx.Err() == nil) {
+			t.Errorf("%s: context error = %v", test.name, first[0].Ctx.Err())
+		}
+
+		var second []Job
+		if test.requeue {
+			s.Queue(1, Visible)
+			second = s.Next()
+			if got := ids(second); !reflect.DeepEqual(got, []int{1}) {
+				t.Fatalf("%s: Next() = %v, want [1]", test.name, got)
+			}
+		}
+		// the first run's result arrives late
+		if got := s.Done(1, first[0].Ticket); got != test.done {
+			t.Errorf("%s: Done(first run) = %v, want %v", test.name, got, test.done)
+		}
+		if test.requeue && !s.Done(1, second[0].Ticket) {
+			t.Errorf("%s: Done(second run) = false", test.name)
+		}
+		if !test.keep {
+			if more := s.Next(); len(more) != 0 {
+				t.Errorf("%s: cancelled items still queued: %v", test.name, ids(more))
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"hackerreader/algolia"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const searchPlaceholder = "words type:story|comment points:N after:YYYY-MM-DD before:YYYY-MM-DD sort:date"
+
+type searchMsg struct {
+	stId    int // the pseudo post holding the results
+	results []int
+}
+
+func (m *model) fetchSearch(stId int, q algolia.Query) tea.Cmd {
+	return func() tea.Msg {
+		results, err := m.searchClient.Search(context.Background(), q)
+		if err != nil {
+			return errMsg{err: err, retry: m.fetchSearch(stId, q)}
+		}
+		return searchMsg{stId: stId, results: results}
+	}
+}
+
+// Runs the search typed in the prompt, listing the results in a new pseudo
+// post.
+func (m *model) submitSearch(value string) tea.Cmd {
+	q, err := algolia.ParseQuery(value)
+	if err != nil {
+		m.prompt.SetError(err)
+		return nil
+	}
+	m.prompt.Close()
+
+	st := m.openPseudo("search", q.String())
+	return m.fetchSearch(st.Id, q)
+}
+
+func (m *model) setSearchResults(msg searchMsg) {
+	st := m.getPost(msg.stId)
+	st.Kids = msg.results
+	st.Descendants = len(msg.results)
+	if m.selected.Peek().(int) == msg.stId {
+		m.moveCursor(m.cursor)
+	}
+}
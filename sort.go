@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"hackerreader/posts"
+	"hackerreader/scheduler"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Lists can be sorted by something other than the order HN gives (their
+// "native" order). Sorting reorders the Kids of the listed post: everything
+// that walks the Kids (cursor, tree mode, find, ...) follows the sorted order.
+
+type sortMode int
+
+const (
+	sortNative sortMode = iota
+	sortScore
+	sortNewest
+	sortComments // stories: comment count (Descendants)
+	sortReplies  // comments: number of direct replies
+	sortDomain
+)
+
+func (s sortMode) String() string {
+	switch s {
+	case sortScore:
+		return "score"
+	case sortNewest:
+		return "newest"
+	case sortComments:
+		return "comments"
+	case sortReplies:
+		return "replies"
+	case sortDomain:
+		return "domain"
+	}
+	return "rank"
+}
+
+// the modes cycled through in each kind of list
+var (
+	storySorts   = []sortMode{sortNative, sortScore, sortNewest, sortComments, sortDomain}
+	commentSorts = []sortMode{sortNative, sortNewest, sortReplies}
+)
+
+// A sorted list remembers its native order, to be able to go back to it.
+type sortedList struct {
+	native []int
+	sorted []int // the Kids we set (if the Kids are something else, they were replaced)
+	queued bool  // the kids that aren't loaded were queued for loading
+}
+
+// Whether the post lists stories (the feeds and pseudo posts) or comments
+func isStoryList(st *posts.Post) bool {
+	return st.Id == rootStoryId || st.IsPseudo()
+}
+
+// The sort mode the kids of the post are shown in
+func (m *model) listSort(st *posts.Post) sortMode {
+	if isStoryList(st) {
+		return m.storySort
+	}
+	return m.commentSort
+}
+
+// Moves to the next sort mode of the selected list (wrapping around to the
+// native order).
+func (m *model) cycleSort() {
+	modes, mode := commentSorts, &m.commentSort
+	if isStoryList(m.getPost(m.selected.Peek().(int))) {
+		modes, mode = storySorts, &m.storySort
+	}
+	i := 0
+	for j, s := range modes {
+		if s == *mode {
+			i = j
+			break
+		}
+	}
+	*mode = modes[(i+1)%len(modes)]
+}
+
+func sameSlice(a []int, b []int) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// Tells if post a goes before post b in the given mode. Both are loaded.
+func sortsBefore(mode sortMode, a *posts.Post, b *posts.Post) bool {
+	switch mode {
+	case sortScore:
+		return a.Score > b.Score
+	case sortNewest:
+		return a.Time > b.Time
+	case sortComments:
+		return a.Descendants > b.Descendants
+	case sortReplies:
+		return a.KidCount() > b.KidCount()
+	case sortDomain:
+		if len(a.Domain) == 0 || len(b.Domain) == 0 {
+			// no domain (e.g.: Ask HN) => last
+			return len(b.Domain) == 0 && len(a.Domain) > 0
+		}
+		return strings.ToLower(a.Domain) < strings.ToLower(b.Domain)
+	}
+	return false
+}
+
+// Returns the kids of the post in HN's order (even if they're sorted).
+func (m *model) nativeKids(st *posts.Post) []int {
+	if list, exists := m.sorted[st.Id]; exists && sameSlice(list.sorted, st.Kids) {
+		return list.native
+	}
+	return st.Kids
+}
+
+// Sorts the kids of the post by its list's sort mode. Kids that aren't loaded
+// yet can't be compared: they go last (in their native order) and are queued
+// for loading (once), so they take their place as they arrive. Returns
+// whether the order changed.
+func (m *model) sortKids(st *posts.Post) bool {
+	mode := m.listSort(st)
+	list, exists := m.sorted[st.Id]
+	if !exists || !sameSlice(list.sorted, st.Kids) {
+		if mode == sortNative {
+			delete(m.sorted, st.Id)
+			return false
+		}
+		list = &sortedList{native: st.Kids}
+		m.sorted[st.Id] = list
+	}
+	prevKids := st.Kids
+	if mode == sortNative {
+		st.Kids = list.native
+		delete(m.sorted, st.Id)
+	} else {
+		kids := append([]int(nil), list.native...)
+		sort.SliceStable(kids, func(i, j int) bool {
+			a, b := m.stories[kids[i]], m.stories[kids[j]]
+			aLoaded := a != nil && a.IsLoaded()
+			bLoaded := b != nil && b.IsLoaded()
+			if !aLoaded || !bLoaded {
+				return aLoaded && !bLoaded
+			}
+			return sortsBefore(mode, a, b)
+		})
+		if !list.queued {
+			for _, kidId := range kids {
+				if kid, exists := m.stories[kidId]; !exists || kid.IsLoading() {
+					m.loadPost(kidId, scheduler.Background)
+				}
+			}
+			list.queued = true
+		}
+		list.sorted = kids
+		st.Kids = kids
+	}
+
+	for i := range prevKids {
+		if prevKids[i] != st.Kids[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the message can change the order of what is on screen: posts
+// arrived, lists were replaced, or keys changed the sort or the list shown.
+func resorts(msg tea.Msg) bool {
+	switch msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg, itemMsg, feedMsg, searchMsg, userMsg, refreshMsg, refreshedMsg:
+		return true
+	}
+	return false
+}
+
+// Sorts what is on screen: the selected list (or, in tree mode, the loaded
+// tree), keeping the cursor on the same post.
+func (m *model) sortShown() {
+	parentStory := m.getPost(m.selected.Peek().(int))
+	hoveredId, hovering := 0, m.cursor < parentStory.KidCount()
+	if hovering {
+		hoveredId = parentStory.Kids[m.cursor]
+	}
+
+	changed := m.sortKids(parentStory)
+	if m.treeMode {
+		var walk func(kids []int)
+		walk = func(kids []int) {
+			for _, kidId := range kids {
+				kid, exists := m.stories[kidId]
+				if exists && kid.IsLoaded() && !m.collapsed[kidId] {
+					changed = m.sortKids(kid) || changed
+					walk(kid.Kids)
+				}
+			}
+		}
+		walk(parentStory.Kids)
+	}
+	if !changed {
+		return
+	}
+	if hovering {
+		m.cursor = max(0, indexOf(parentStory.Kids, hoveredId))
+	}
+	m.setRedraw()
+}
+
+// Describes the sort of the selected list for the title bar ("" => native).
+func (m *model) sortView() string {
+	parentStory := m.getPost(m.selected.Peek().(int))
+	mode := m.listSort(parentStory)
+	if mode == sortNative {
+		return ""
+	}
+	loading := 0
+	for _, kidId := range parentStory.Kids {
+		if kid, exists := m.stories[kidId]; exists && kid.IsLoading() {
+			loading++
+		}
+	}
+	if loading > 0 {
+		return fmt.Sprintf(" (sorted by %s, %d loading)", mode, loading)
+	}
+	return fmt.Sprintf(" (sorted by %s)", mode)
+}
@@ -6,36 +6,16 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-const (
-	hnOrange = "#FF6600"
-	// dracula: https://github.com/dracula/dracula-theme/
-	background = "#282a37"
-	foreground = "#f8f8f2"
-	secondary  = "#867f74"
-	cyan       = "#8be9fd"
-	cyan2      = "#6EEFC0"
-	green      = "#50fa7b"
-	orange     = "#ffb86c"
-	pink       = "#ff79c6"
-	purple     = "#bd93f9"
-	red        = "#ff5555"
-	yellow     = "#f1fa8c"
-)
-
+// All these are (re)built from the current theme by Apply.
 var (
 	// colors
-	ForegroundColor = lipgloss.Color(foreground) // #EEEEEE
-	SecondaryColor  = lipgloss.Color(secondary)
-	GreenColor      = lipgloss.Color(green) // #3ED71C
-	CyanColor       = lipgloss.Color(cyan)
-	HNOrange        = lipgloss.Color(hnOrange)
+	ForegroundColor lipgloss.TerminalColor
+	SecondaryColor  lipgloss.TerminalColor
+	GreenColor      lipgloss.TerminalColor // hovered post
+	CyanColor       lipgloss.TerminalColor
+	HNOrange        lipgloss.TerminalColor
 	// title bar
-	TitleBar = lipgloss.NewStyle().
-			Background(HNOrange).
-			Foreground(ForegroundColor).
-			Bold(true).
-			PaddingLeft(1).
-			PaddingRight(1)
+	TitleBar lipgloss.Style
 	// main item
 	MainItemBorder = lipgloss.Border{
 		Top:         "═",
@@ -47,17 +27,10 @@ var (
 		BottomLeft:  "╚",
 		BottomRight: "╝",
 	}
-	MainItem = lipgloss.NewStyle().
-			Border(MainItemBorder).
-			BorderForeground(CyanColor)
-	VoteBar = lipgloss.NewStyle().
-		Foreground(lipgloss.Color(cyan2)).
-		Render
+	MainItem lipgloss.Style
+	VoteBar  func(str string) string
 	// check mark
-	Checkmark = lipgloss.NewStyle().
-			Foreground(GreenColor).
-			Bold(true).
-			Render
+	Checkmark func(str string) string
 	// list items
 	ListItemBorder = lipgloss.Border{
 		Top:         "─",
@@ -69,32 +42,104 @@ var (
 		BottomLeft:  "├",
 		BottomRight: "┤",
 	}
-	ListItem = lipgloss.NewStyle().
-			Border(ListItemBorder).
-			BorderForeground(ForegroundColor)
+	ListItem lipgloss.Style
+	// tree mode (each depth has its own color)
+	DepthColors []lipgloss.TerminalColor
 	// url stuff
-	UrlStyle = lipgloss.NewStyle().
-			Foreground(SecondaryColor).
-			Italic(true)
+	UrlStyle lipgloss.Style
 	// other
-	PrimaryStyle = lipgloss.NewStyle().
-			Foreground(ForegroundColor)
-	SecondaryStyle = lipgloss.NewStyle().
-			Foreground(SecondaryColor)
+	PrimaryStyle   lipgloss.Style
+	SecondaryStyle lipgloss.Style
+	ErrorStyle     lipgloss.Style
+	NewStyle       lipgloss.Style // what's new since the last visit
 	// spinner
 	SpinnerSpinner = spinner.Line
-	SpinnerStyle   = lipgloss.NewStyle().
-			Foreground(HNOrange)
+	SpinnerStyle   lipgloss.Style
 	// md
-	MdStyleConfig = ansi.StyleConfig{
+	MdStyleConfig ansi.StyleConfig
+	// name of the theme in use
+	ThemeName string
+)
+
+func init() {
+	Apply(Dracula)
+}
+
+// Apply makes t the theme of every style in this package. It has to be
+// called before the UI is built (styles copied before don't change).
+func Apply(t Theme) {
+	ThemeName = t.Name
+	ForegroundColor = color(t.Foreground)
+	SecondaryColor = color(t.Secondary)
+	GreenColor = color(t.Hover)
+	CyanColor = color(t.MainBorder)
+	HNOrange = color(t.TitleBackground)
+
+	TitleBar = lipgloss.NewStyle().
+		Background(HNOrange).
+		Foreground(color(t.TitleForeground)).
+		Bold(true).
+		PaddingLeft(1).
+		PaddingRight(1)
+	if len(t.TitleBackground) == 0 {
+		// no colors => the title bar still has to stand out
+		TitleBar = TitleBar.Reverse(true)
+	}
+	MainItem = lipgloss.NewStyle().
+		Border(MainItemBorder).
+		BorderForeground(CyanColor)
+	VoteBar = lipgloss.NewStyle().
+		Foreground(color(t.VoteBar)).
+		Render
+	Checkmark = lipgloss.NewStyle().
+		Foreground(GreenColor).
+		Bold(true).
+		Render
+	ListItem = lipgloss.NewStyle().
+		Border(ListItemBorder).
+		BorderForeground(color(t.ListBorder))
+
+	DepthColors = nil
+	for _, c := range t.Depth {
+		DepthColors = append(DepthColors, color(c))
+	}
+	if len(DepthColors) == 0 {
+		DepthColors = []lipgloss.TerminalColor{lipgloss.NoColor{}}
+	}
+
+	UrlStyle = lipgloss.NewStyle().
+		Foreground(SecondaryColor).
+		Italic(true)
+	PrimaryStyle = lipgloss.NewStyle().
+		Foreground(ForegroundColor)
+	SecondaryStyle = lipgloss.NewStyle().
+		Foreground(SecondaryColor)
+	ErrorStyle = lipgloss.NewStyle().
+		Foreground(color(t.Error))
+	if len(t.Error) == 0 {
+		ErrorStyle = ErrorStyle.Bold(true)
+	}
+	NewStyle = lipgloss.NewStyle().
+		Foreground(color(t.New))
+	if len(t.New) == 0 {
+		NewStyle = NewStyle.Underline(true)
+	}
+	SpinnerStyle = lipgloss.NewStyle().
+		Foreground(color(t.Spinner))
+
+	MdStyleConfig = mdStyleConfig(t)
+}
+
+func mdStyleConfig(t Theme) ansi.StyleConfig {
+	return ansi.StyleConfig{
 		Document: ansi.StyleBlock{
 			StylePrimitive: ansi.StylePrimitive{
-				Color: stringPtr(foreground),
+				Color: colorPtr(t.Markdown.Text),
 			},
 		},
 		BlockQuote: ansi.StyleBlock{
 			StylePrimitive: ansi.StylePrimitive{
-				Color: stringPtr(purple),
+				Color: colorPtr(t.Markdown.BlockQuote),
 			},
 			Indent:      uintPtr(1),
 			IndentToken: stringPtr("│ "),
@@ -110,6 +155,7 @@ var (
 		},
 		Heading: ansi.StyleBlock{
 			StylePrimitive: ansi.StylePrimitive{
+				Color:       colorPtr(t.Markdown.Heading),
 				BlockSuffix: "\n",
 			},
 		},
@@ -147,11 +193,11 @@ var (
 			CrossedOut: boolPtr(true),
 		},
 		Emph: ansi.StylePrimitive{
-			Color:  stringPtr(yellow),
+			Color:  colorPtr(t.Markdown.Emph),
 			Italic: boolPtr(true),
 		},
 		Strong: ansi.StylePrimitive{
-			Color: stringPtr(orange),
+			Color: colorPtr(t.Markdown.Strong),
 			Bold:  boolPtr(true),
 		},
 		HorizontalRule: ansi.StylePrimitive{
@@ -168,6 +214,7 @@ var (
 			Unticked: "[ ] ",
 		},
 		Link: ansi.StylePrimitive{
+			Color:       colorPtr(t.Markdown.Link),
 			Underline:   boolPtr(true),
 			BlockPrefix: "(",
 			BlockSuffix: ")",
@@ -180,6 +227,7 @@ var (
 		},
 		Code: ansi.StyleBlock{
 			StylePrimitive: ansi.StylePrimitive{
+				Color:       colorPtr(t.Markdown.Code),
 				BlockPrefix: "`",
 				BlockSuffix: "`",
 			},
@@ -190,84 +238,84 @@ var (
 			},
 			Chroma: &ansi.Chroma{
 				Text: ansi.StylePrimitive{
-					Color: stringPtr(foreground),
+					Color: colorPtr(t.Chroma.Text),
 				},
 				Error: ansi.StylePrimitive{
-					Color: stringPtr(foreground),
+					Color: colorPtr(t.Chroma.Error),
 				},
 				Comment: ansi.StylePrimitive{
-					Color: stringPtr(secondary),
+					Color: colorPtr(t.Chroma.Comment),
 				},
 				CommentPreproc: ansi.StylePrimitive{
-					Color: stringPtr(pink),
+					Color: colorPtr(t.Chroma.CommentPreproc),
 				},
 				Keyword: ansi.StylePrimitive{
-					Color: stringPtr(pink),
+					Color: colorPtr(t.Chroma.Keyword),
 				},
 				KeywordReserved: ansi.StylePrimitive{
-					Color: stringPtr(pink),
+					Color: colorPtr(t.Chroma.KeywordReserved),
 				},
 				KeywordNamespace: ansi.StylePrimitive{
-					Color: stringPtr(pink),
+					Color: colorPtr(t.Chroma.KeywordNamespace),
 				},
 				KeywordType: ansi.StylePrimitive{
-					Color: stringPtr(cyan),
+					Color: colorPtr(t.Chroma.KeywordType),
 				},
 				Operator: ansi.StylePrimitive{
-					Color: stringPtr(pink),
+					Color: colorPtr(t.Chroma.Operator),
 				},
 				Punctuation: ansi.StylePrimitive{
-					Color: stringPtr(foreground),
+					Color: colorPtr(t.Chroma.Punctuation),
 				},
 				Name: ansi.StylePrimitive{
-					Color: stringPtr(cyan),
+					Color: colorPtr(t.Chroma.Name),
 				},
 				NameBuiltin: ansi.StylePrimitive{
-					Color: stringPtr(cyan),
+					Color: colorPtr(t.Chroma.NameBuiltin),
 				},
 				NameTag: ansi.StylePrimitive{
-					Color: stringPtr(pink),
+					Color: colorPtr(t.Chroma.NameTag),
 				},
 				NameAttribute: ansi.StylePrimitive{
-					Color: stringPtr(green),
+					Color: colorPtr(t.Chroma.NameAttribute),
 				},
 				NameClass: ansi.StylePrimitive{
-					Color: stringPtr(cyan),
+					Color: colorPtr(t.Chroma.NameClass),
 				},
 				NameConstant: ansi.StylePrimitive{
-					Color: stringPtr(purple),
+					Color: colorPtr(t.Chroma.NameConstant),
 				},
 				NameDecorator: ansi.StylePrimitive{
-					Color: stringPtr(green),
+					Color: colorPtr(t.Chroma.NameDecorator),
 				},
 				NameFunction: ansi.StylePrimitive{
-					Color: stringPtr(green),
+					Color: colorPtr(t.Chroma.NameFunction),
 				},
 				LiteralNumber: ansi.StylePrimitive{
-					Color: stringPtr(cyan2),
+					Color: colorPtr(t.Chroma.LiteralNumber),
 				},
 				LiteralString: ansi.StylePrimitive{
-					Color: stringPtr(yellow),
+					Color: colorPtr(t.Chroma.LiteralString),
 				},
 				LiteralStringEscape: ansi.StylePrimitive{
-					Color: stringPtr(pink),
+					Color: colorPtr(t.Chroma.LiteralStringEscape),
 				},
 				GenericDeleted: ansi.StylePrimitive{
-					Color: stringPtr(red),
+					Color: colorPtr(t.Chroma.GenericDeleted),
 				},
 				GenericEmph: ansi.StylePrimitive{
-					Color:  stringPtr(yellow),
+					Color:  colorPtr(t.Chroma.GenericEmph),
 					Italic: boolPtr(true),
 				},
 				GenericInserted: ansi.StylePrimitive{
-					Color: stringPtr(green),
+					Color: colorPtr(t.Chroma.GenericInserted),
 				},
 				GenericStrong: ansi.StylePrimitive{
-					Color: stringPtr(orange),
+					Color: colorPtr(t.Chroma.GenericStrong),
 					Bold:  boolPtr(true),
 				},
 				GenericSubheading: ansi.StylePrimitive{
-					Color: stringPtr(purple),
+					Color: colorPtr(t.Chroma.GenericSubheading),
 				},
 			},
 		},
@@ -278,7 +326,29 @@ var (
 		},
 		DefinitionDescription: ansi.StylePrimitive{},
 	}
-)
+}
+
+func DepthGutter(depth int) string {
+	return lipgloss.NewStyle().
+		Foreground(DepthColors[depth%len(DepthColors)]).
+		Render("│ ")
+}
+
+// Returns the lipgloss color for c ("" => the terminal's default).
+func color(c string) lipgloss.TerminalColor {
+	if len(c) == 0 {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
+
+// Returns the glamour color for c ("" => the terminal's default).
+func colorPtr(c string) *string {
+	if len(c) == 0 {
+		return nil
+	}
+	return &c
+}
 
 func boolPtr(b bool) *bool       { return &b }
 func stringPtr(s string) *string { return &s }
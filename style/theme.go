@@ -0,0 +1,309 @@
+package style
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A Theme is the set of colors used by the app. Colors are anything lipgloss
+// understands: "#rrggbb" or an ANSI color number (e.g.: "208"). An empty color
+// means the terminal's default one.
+//
+// Themes can be loaded from JSON or TOML files (see LoadTheme). Both use the
+// field names in the tags below, e.g.:
+//
+//	name = "solarized"
+//	base = "light"
+//	foreground = "#657b83"
+//	depth = ["#268bd2", "#859900", "#cb4b16"]
+//
+//	[markdown]
+//	emph = "#b58900"
+//
+//	[chroma]
+//	keyword = "#859900"
+type Theme struct {
+	Name            string   `json:"name" toml:"name"`
+	Base            string   `json:"base" toml:"base"`             // built-in theme the missing colors come from
+	Foreground      string   `json:"foreground" toml:"foreground"` // text
+	Secondary       string   `json:"secondary" toml:"secondary"`   // authors, dates, urls, ...
+	Error           string   `json:"error" toml:"error"`
+	TitleForeground string   `json:"title_foreground" toml:"title_foreground"`
+	TitleBackground string   `json:"title_background" toml:"title_background"`
+	MainBorder      string   `json:"main_border" toml:"main_border"` // border of the selected post
+	ListBorder      string   `json:"list_border" toml:"list_border"` // border of the posts in the list
+	Hover           string   `json:"hover" toml:"hover"`             // border and cursor of the hovered post
+	VoteBar         string   `json:"vote_bar" toml:"vote_bar"`       // poll results
+	Spinner         string   `json:"spinner" toml:"spinner"`
+	New             string   `json:"new" toml:"new"`     // comments since the last visit
+	Depth           []string `json:"depth" toml:"depth"` // tree mode gutters (cycled through)
+	Markdown        Markdown `json:"markdown" toml:"markdown"`
+	Chroma          Chroma   `json:"chroma" toml:"chroma"`
+}
+
+// Markdown has the colors of the posts' text.
+type Markdown struct {
+	Text       string `json:"text" toml:"text"`
+	BlockQuote string `json:"block_quote" toml:"block_quote"`
+	Heading    string `json:"heading" toml:"heading"`
+	Emph       string `json:"emph" toml:"emph"`
+	Strong     string `json:"strong" toml:"strong"`
+	Link       string `json:"link" toml:"link"`
+	Code       string `json:"code" toml:"code"`
+}
+
+// Chroma has the colors of the syntax highlighting in code blocks.
+type Chroma struct {
+	Text                string `json:"text" toml:"text"`
+	Error               string `json:"error" toml:"error"`
+	Comment             string `json:"comment" toml:"comment"`
+	CommentPreproc      string `json:"comment_preproc" toml:"comment_preproc"`
+	Keyword             string `json:"keyword" toml:"keyword"`
+	KeywordReserved     string `json:"keyword_reserved" toml:"keyword_reserved"`
+	KeywordNamespace    string `json:"keyword_namespace" toml:"keyword_namespace"`
+	KeywordType         string `json:"keyword_type" toml:"keyword_type"`
+	Operator            string `json:"operator" toml:"operator"`
+	Punctuation         string `json:"punctuation" toml:"punctuation"`
+	Name                string `json:"name" toml:"name"`
+	NameBuiltin         string `json:"name_builtin" toml:"name_builtin"`
+	NameTag             string `json:"name_tag" toml:"name_tag"`
+	NameAttribute       string `json:"name_attribute" toml:"name_attribute"`
+	NameClass           string `json:"name_class" toml:"name_class"`
+	NameConstant        string `json:"name_constant" toml:"name_constant"`
+	NameDecorator       string `json:"name_decorator" toml:"name_decorator"`
+	NameFunction        string `json:"name_function" toml:"name_function"`
+	LiteralNumber       string `json:"literal_number" toml:"literal_number"`
+	LiteralString       string `json:"literal_string" toml:"literal_string"`
+	LiteralStringEscape string `json:"literal_string_escape" toml:"literal_string_escape"`
+	GenericDeleted      string `json:"generic_deleted" toml:"generic_deleted"`
+	GenericEmph         string `json:"generic_emph" toml:"generic_emph"`
+	GenericInserted     string `json:"generic_inserted" toml:"generic_inserted"`
+	GenericStrong       string `json:"generic_strong" toml:"generic_strong"`
+	GenericSubheading   string `json:"generic_subheading" toml:"generic_subheading"`
+}
+
+const hnOrange = "#FF6600"
+
+// Dracula is the default theme: https://github.com/dracula/dracula-theme/
+var Dracula = func() Theme {
+	const (
+		foreground = "#f8f8f2"
+		secondary  = "#867f74"
+		cyan       = "#8be9fd"
+		cyan2      = "#6EEFC0"
+		green      = "#50fa7b"
+		orange     = "#ffb86c"
+		pink       = "#ff79c6"
+		purple     = "#bd93f9"
+		red        = "#ff5555"
+		yellow     = "#f1fa8c"
+	)
+	return Theme{
+		Name:            "dracula",
+		Foreground:      foreground,
+		Secondary:       secondary,
+		Error:           red,
+		TitleForeground: foreground,
+		TitleBackground: hnOrange,
+		MainBorder:      cyan,
+		ListBorder:      foreground,
+		Hover:           green,
+		VoteBar:         cyan2,
+		Spinner:         hnOrange,
+		New:             orange,
+		Depth:           []string{cyan, green, orange, pink, purple, yellow},
+		Markdown: Markdown{
+			Text:       foreground,
+			BlockQuote: purple,
+			Emph:       yellow,
+			Strong:     orange,
+		},
+		Chroma: Chroma{
+			Text:                foreground,
+			Error:               foreground,
+			Comment:             secondary,
+			CommentPreproc:      pink,
+			Keyword:             pink,
+			KeywordReserved:     pink,
+			KeywordNamespace:    pink,
+			KeywordType:         cyan,
+			Operator:            pink,
+			Punctuation:         foreground,
+			Name:                cyan,
+			NameBuiltin:         cyan,
+			NameTag:             pink,
+			NameAttribute:       green,
+			NameClass:           cyan,
+			NameConstant:        purple,
+			NameDecorator:       green,
+			NameFunction:        green,
+			LiteralNumber:       cyan2,
+			LiteralString:       yellow,
+			LiteralStringEscape: pink,
+			GenericDeleted:      red,
+			GenericEmph:         yellow,
+			GenericInserted:     green,
+			GenericStrong:       orange,
+			GenericSubheading:   purple,
+		},
+	}
+}()
+
+// Light is for terminals with a light background (dark colors only).
+var Light = func() Theme {
+	const (
+		foreground = "#24292f"
+		secondary  = "#6e7781"
+		blue       = "#0550ae"
+		teal       = "#1b7c83"
+		green      = "#116329"
+		orange     = "#953800"
+		pink       = "#bf3989"
+		purple     = "#8250df"
+		red        = "#cf222e"
+		brown      = "#7d4e00"
+	)
+	return Theme{
+		Name:            "light",
+		Foreground:      foreground,
+		Secondary:       secondary,
+		Error:           red,
+		TitleForeground: "#ffffff",
+		TitleBackground: hnOrange,
+		MainBorder:      blue,
+		ListBorder:      secondary,
+		Hover:           green,
+		VoteBar:         teal,
+		Spinner:         hnOrange,
+		New:             orange,
+		Depth:           []string{blue, green, orange, pink, purple, brown},
+		Markdown: Markdown{
+			Text:       foreground,
+			BlockQuote: purple,
+			Emph:       brown,
+			Strong:     orange,
+		},
+		Chroma: Chroma{
+			Text:                foreground,
+			Error:               red,
+			Comment:             secondary,
+			CommentPreproc:      pink,
+			Keyword:             red,
+			KeywordReserved:     red,
+			KeywordNamespace:    red,
+			KeywordType:         blue,
+			Operator:            red,
+			Punctuation:         foreground,
+			Name:                foreground,
+			NameBuiltin:         blue,
+			NameTag:             green,
+			NameAttribute:       blue,
+			NameClass:           orange,
+			NameConstant:        blue,
+			NameDecorator:       purple,
+			NameFunction:        purple,
+			LiteralNumber:       blue,
+			LiteralString:       teal,
+			LiteralStringEscape: pink,
+			GenericDeleted:      red,
+			GenericEmph:         brown,
+			GenericInserted:     green,
+			GenericStrong:       orange,
+			GenericSubheading:   purple,
+		},
+	}
+}()
+
+// Mono has no colors at all: only bold/italic/underline/reverse text. It's the
+// default when NO_COLOR is set (https://no-color.org/).
+var Mono = Theme{Name: "mono"}
+
+// DefaultTheme is the theme used when none is configured.
+func DefaultTheme() Theme {
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return Mono
+	}
+	return Dracula
+}
+
+// Themes are the built-in themes, by name.
+var Themes = map[string]Theme{
+	Dracula.Name: Dracula,
+	Light.Name:   Light,
+	Mono.Name:    Mono,
+}
+
+// Returns the names of the built-in themes (sorted).
+func ThemeNames() []string {
+	var names []string
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTheme returns the built-in theme with the given name or, if there's
+// none, loads the theme file at the given path (.json or .toml). Colors
+// missing from a file are taken from its base theme (dracula by default).
+func LoadTheme(nameOrPath string) (Theme, error) {
+	if t, exists := Themes[nameOrPath]; exists {
+		return t, nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(nameOrPath))
+	if ext != ".json" && ext != ".toml" {
+		return Theme{}, fmt.Errorf("unknown theme %q (expected one of %s or a .json/.toml file)",
+			nameOrPath, strings.Join(ThemeNames(), ", "))
+	}
+	data, err := ioutil.ReadFile(nameOrPath)
+	if err != nil {
+		return Theme{}, err
+	}
+
+	// the base has to be known before decoding the rest on top of it
+	var base Theme
+	if err = decodeTheme(ext, data, &base); err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", nameOrPath, err)
+	}
+	if len(base.Base) == 0 {
+		base.Base = Dracula.Name
+	}
+	t, exists := Themes[base.Base]
+	if !exists {
+		return Theme{}, fmt.Errorf("%s: unknown base theme %q", nameOrPath, base.Base)
+	}
+	t.Name = strings.TrimSuffix(filepath.Base(nameOrPath), filepath.Ext(nameOrPath))
+	t.Depth = nil // given lists replace the base's one (instead of being merged)
+	if err = decodeTheme(ext, data, &t); err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", nameOrPath, err)
+	}
+	if t.Depth == nil {
+		t.Depth = Themes[base.Base].Depth
+	}
+	return t, nil
+}
+
+func decodeTheme(ext string, data []byte, t *Theme) error {
+	if ext == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(t)
+	}
+	md, err := toml.Decode(string(data), t)
+	if err == nil && len(md.Undecoded()) > 0 {
+		var keys []string
+		for _, key := range md.Undecoded() {
+			keys = append(keys, key.String())
+		}
+		err = fmt.Errorf("unknown settings: %s", strings.Join(keys, ", "))
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package thread
+
+import (
+	"context"
+	"errors"
+	"hackerreader/hn"
+	"hackerreader/posts"
+	"sync"
+)
+
+// Fetching whole threads (or lists of items) at once, outside of the UI: e.g.
+// for the command line subcommands and the exports. At most `limit` items are fetched at the
+// same time.
+
+// Load gets the raw JSON of an item (e.g.: hn.Client.Item, maybe behind the
+// cache).
+type Load func(ctx context.Context, id int) ([]byte, error)
+
+// Known posts (e.g.: the ones the reader already loaded) aren't fetched again.
+type Known map[int]posts.Post
+
+// A Thread is a post with its replies (as deep as they were fetched).
+type Thread struct {
+	posts.Post
+	Replies []*Thread
+}
+
+type fetcher struct {
+	load  Load
+	known Known
+	slots chan struct{}
+}
+
+func newFetcher(load Load, known Known, limit int) *fetcher {
+	if limit < 1 {
+		limit = 1
+	}
+	return &fetcher{load: load, known: known, slots: make(chan struct{}, limit)}
+}
+
+// Fetches a single item (unless it's known), waiting for a free slot. Items
+// that don't exist are nil.
+func (f *fetcher) post(ctx context.Context, id int) (*posts.Post, error) {
+	if st, isKnown := f.known[id]; isKnown {
+		return &st, nil
+	}
+	select {
+	case f.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	bodyBytes, err := f.load(ctx, id)
+	<-f.slots
+	if errors.Is(err, hn.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	st := posts.FromJSON(bodyBytes, nil)
+	return &st, nil
+}
+
+// Fetches the items in parallel. Results are in the same order as ids (nil
+// for the items that don't exist). The first error cancels everything.
+func (f *fetcher) posts(ctx context.Context, ids []int) ([]*posts.Post, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*posts.Post, len(ids))
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id int) {
+			defer wg.Done()
+			st, err := f.post(ctx, id)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = st
+		}(i, id)
+	}
+	wg.Wait()
+	return results, firstErr
+}
+
+// Items fetches the given items (the ones that don't exist are left out).
+func Items(ctx context.Context, load Load, ids []int, limit int) ([]posts.Post, error) {
+	results, err := newFetcher(load, nil, limit).posts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]posts.Post, 0, len(results))
+	for _, st := range results {
+		if st != nil {
+			items = append(items, *st)
+		}
+	}
+	return items, nil
+}
+
+// Fetch fetches the item and its replies, down to depth levels of replies
+// (0 => only the item, negative => the whole thread). Known posts are used
+// as they are.
+func Fetch(ctx context.Context, load Load, known Known, id int, depth int, limit int) (*Thread, error) {
+	f := newFetcher(load, known, limit)
+	st, err := f.post(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return nil, hn.ErrNotFound
+	}
+	t := &Thread{Post: *st}
+	if err = f.replies(ctx, t, depth); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Fetches the replies of t, a level at a time: each level is fetched in
+// parallel (so a wide thread doesn't wait on its deepest branch).
+func (f *fetcher) replies(ctx context.Context, t *Thread, depth int) error {
+	level := []*Thread{t}
+	for ; depth != 0 && len(level) > 0; depth-- {
+		var ids []int
+		var parents []*Thread
+		for _, parent := range level {
+			for _, kid := range parent.Kids {
+				ids = append(ids, kid)
+				parents = append(parents, parent)
+			}
+		}
+		results, err := f.posts(ctx, ids)
+		if err != nil {
+			return err
+		}
+		var next []*Thread
+		for i, st := range results {
+			if st == nil {
+				continue
+			}
+			reply := &Thread{Post: *st}
+			parents[i].Replies = append(parents[i].Replies, reply)
+			next = append(next, reply)
+		}
+		level = next
+	}
+	return nil
+}
+
+// Walk calls fn for every post of the thread, depth first (depth 0 is the
+// thread's root).
+func (t *Thread) Walk(fn func(t *Thread, depth int)) {
+	t.walk(fn, 0)
+}
+
+func (t *Thread) walk(fn func(t *Thread, depth int), depth int) {
+	fn(t, depth)
+	for _, reply := range t.Replies {
+		reply.walk(fn, depth+1)
+	}
+}
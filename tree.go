@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"hackerreader/posts"
+	"hackerreader/scheduler"
+	"hackerreader/style"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Tree mode shows the whole (loaded) subtree of the selected post, instead of
+// only its kids. The cursor walks the tree in depth-first order.
+
+type treeNode struct {
+	stId  int
+	depth int
+}
+
+// Flattens the loaded subtree of the post (depth-first), skipping the kids of
+// collapsed and hidden posts. Nothing is loaded here (see loadTree).
+func (m *model) flattenTree(st *posts.Post) []treeNode {
+	var nodes []treeNode
+	var walk func(kids []int, depth int)
+	walk = func(kids []int, depth int) {
+		for _, kidId := range kids {
+			nodes = append(nodes, treeNode{stId: kidId, depth: depth})
+			if kid, exists := m.stories[kidId]; exists && kid.IsLoaded() && m.expandedInTree(kid) {
+				walk(kid.Kids, depth+1)
+			}
+		}
+	}
+	walk(st.Kids, 0)
+	return nodes
+}
+
+// Whether the kids of the post are part of the tree
+func (m *model) expandedInTree(st *posts.Post) bool {
+	return !m.collapsed[st.Id] && !st.Hidden && st.Filter != posts.Killed
+}
+
+// Queues the loading (in the background) of the posts of the tree that aren't
+// loaded yet. The ones still loading go on when they arrive (see
+// loadTreeKids).
+func (m *model) loadTree(kids []int) {
+	for _, kidId := range kids {
+		if kid := m.loadPost(kidId, scheduler.Background); kid.IsLoaded() && m.expandedInTree(kid) {
+			m.loadTree(kid.Kids)
+		}
+	}
+}
+
+// Loads the subtree of a post that just arrived, if it is part of the tree.
+func (m *model) loadTreeKids(st *posts.Post) {
+	if !m.treeMode {
+		return
+	}
+	parentStory := m.getPost(m.selected.Peek().(int))
+	for ancestor := st; indexOf(parentStory.Kids, ancestor.Id) < 0; {
+		parent, exists := m.stories[ancestor.Parent]
+		if !exists || !parent.IsLoaded() || !m.expandedInTree(parent) {
+			// not in the tree (or not shown)
+			return
+		}
+		ancestor = parent
+	}
+	if m.expandedInTree(st) {
+		m.loadTree(st.Kids)
+	}
+}
+
+// Index of the node under the cursor (first node if it is gone)
+func (m *model) treeCursorIndex(nodes []treeNode) int {
+	for i, node := range nodes {
+		if node.stId == m.treeCursor {
+			return i
+		}
+	}
+	return 0
+}
+
+func (m *model) moveTreeCursor(nodes []treeNode, i int) {
+	if len(nodes) == 0 {
+		return
+	}
+	m.treeCursor = nodes[max(0, min(i, len(nodes)-1))].stId
+}
+
+func (m *model) toggleTreeMode() {
+	parentStory := m.getPost(m.selected.Peek().(int))
+	if m.treeMode {
+		// back to the list => the cursor goes to the top-level ancestor
+		nodes := m.flattenTree(parentStory)
+		for i := m.treeCursorIndex(nodes); i >= 0 && i < len(nodes); i-- {
+			if nodes[i].depth == 0 {
+				m.cursor = max(0, indexOf(parentStory.Kids, nodes[i].stId))
+				break
+			}
+		}
+		m.treeMode = false
+		return
+	}
+
+	if m.selected.Len() > 1 && parentStory.HasKids() {
+		// the root list isn't a thread
+		m.treeMode = true
+		m.treeCursor = parentStory.Kids[m.cursor]
+		m.loadTree(parentStory.Kids)
+	}
+}
+
+// Returns false for keys that aren't specific to tree mode
+func (m *model) treeKeyHandler(msg tea.KeyMsg) bool {
+	parentStory := m.getPost(m.selected.Peek().(int))
+	nodes := m.flattenTree(parentStory)
+	if len(nodes) == 0 {
+		return false
+	}
+	i := m.treeCursorIndex(nodes)
+	node := nodes[i]
+	st := m.getPost(node.stId)
+
+	switch {
+	case key.Matches(msg, m.keys.Top):
+		m.moveTreeCursor(nodes, 0)
+	case key.Matches(msg, m.keys.Bottom):
+		m.moveTreeCursor(nodes, len(nodes)-1)
+	case key.Matches(msg, m.keys.PageUp):
+		m.moveTreeCursor(nodes, i-10)
+	case key.Matches(msg, m.keys.PageDown):
+		m.moveTreeCursor(nodes, i+10)
+	case key.Matches(msg, m.keys.Down):
+		m.moveTreeCursor(nodes, i+1)
+	case key.Matches(msg, m.keys.Up):
+		m.moveTreeCursor(nodes, i-1)
+	case key.Matches(msg, m.keys.TreeToggle):
+		if st.HasKids() {
+			m.collapsed[node.stId] = !m.collapsed[node.stId]
+			m.loadTreeKids(st)
+		}
+	case key.Matches(msg, m.keys.TreeExpand):
+		delete(m.collapsed, node.stId)
+		m.loadTreeKids(st)
+	case key.Matches(msg, m.keys.TreeCollapse): // or go to the parent if there's nothing to collapse
+		if st.HasKids() && !m.collapsed[node.stId] {
+			m.collapsed[node.stId] = true
+		} else if node.depth > 0 {
+			for j := i - 1; j >= 0; j-- {
+				if nodes[j].depth == node.depth-1 {
+					m.moveTreeCursor(nodes, j)
+					break
+				}
+			}
+		} else {
+			// top-level => let it go back
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *model) treeItemView(node treeNode, highlight bool, w int) string {
+	st := m.getPost(node.stId)
+
+	cursor := "  "
+	if highlight {
+		cursor = style.Checkmark("> ")
+	}
+	gutter := ""
+	for depth := 0; depth < node.depth; depth++ {
+		gutter += style.DepthGutter(depth)
+	}
+
+	itemStr := st.View(highlight, false, w-lipgloss.Width(cursor+gutter)-1, m.stories)
+	if m.collapsed[node.stId] && st.HasKids() {
+		itemStr = lipgloss.JoinVertical(lipgloss.Left, itemStr,
+			style.SecondaryStyle.Copy().
+				Bold(highlight).
+				Render(fmt.Sprintf("[+] %d replies", st.KidCount())))
+	}
+	// the gutter goes along the whole post + a spacing line
+	h := lipgloss.Height(itemStr) + 1
+	gutterCol := strings.TrimSuffix(strings.Repeat(gutter+style.DepthGutter(node.depth)+"\n", h), "\n")
+	cursorCol := strings.TrimSuffix(cursor+strings.Repeat("\n", h), "\n")
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, cursorCol, gutterCol, itemStr)
+}
+
+func (m *model) treeView(parentStory *posts.Post, maxH int) string {
+	nodes := m.flattenTree(parentStory)
+	if len(nodes) == 0 {
+		return ""
+	}
+	cursor := m.treeCursorIndex(nodes)
+	itemList, shown := centeredList(len(nodes), cursor, maxH, func(i int) string {
+		return m.treeItemView(nodes[i], i == cursor, m.cappedW)
+	})
+	for _, i := range shown {
+		*m.onScreen = append(*m.onScreen, nodes[i].stId)
+	}
+	return itemList
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"hackerreader/hn"
+	"hackerreader/posts"
+	"hackerreader/style"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Trends of the root list: each fetch of a feed is ranked against the previous
+// one, so the stories show how they moved (↑n, ↓ or new). The points and
+// comments of the stories are sampled whenever they are fetched during the
+// session: the sparklines show how fast they grow.
+
+const (
+	maxSamples     = 9 // => 8 bars
+	minSampleSpace = time.Minute
+	rankMoveW      = 5 // e.g.: "↑123 "
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+type ranking struct {
+	prev map[int]int // id => rank in the previous fetch (nil => first fetch)
+	cur  map[int]int
+}
+
+type sample struct {
+	at          time.Time
+	score       int
+	descendants int
+}
+
+// Records the new order of the feed's stories (in HN's order).
+func (m *model) rankFeed(feed hn.Feed, stories []int) {
+	if feed == savedFeed {
+		// ordered by us
+		return
+	}
+	r, exists := m.rankings[feed]
+	if !exists {
+		r = &ranking{}
+		m.rankings[feed] = r
+	}
+	r.prev = r.cur
+	r.cur = make(map[int]int, len(stories))
+	for i, stId := range stories {
+		r.cur[stId] = i
+	}
+}
+
+// How the story moved in the feed since the previous fetch ("" if it didn't,
+// or if there's no previous fetch).
+func (m *model) rankMoveView(feed hn.Feed, stId int) string {
+	r, exists := m.rankings[feed]
+	if !exists || r.prev == nil {
+		return ""
+	}
+	prevRank, known := r.prev[stId]
+	rank := r.cur[stId]
+	switch {
+	case !known:
+		return style.NewStyle.Render("new")
+	case prevRank > rank:
+		return lipgloss.NewStyle().Foreground(style.GreenColor).Render(fmt.Sprintf("↑%d", prevRank-rank))
+	case prevRank < rank:
+		return style.SecondaryStyle.Render("↓")
+	}
+	return ""
+}
+
+// Whether the rows of the feed have a column for rankMoveView.
+func (m *model) ranked(feed hn.Feed) bool {
+	r, exists := m.rankings[feed]
+	return exists && r.prev != nil
+}
+
+// Takes a sample of the story's points and comments (just fetched).
+func (m *model) sampleTrend(st *posts.Post) {
+	if !st.IsLoaded() || st.IsPseudo() || st.Storytype == "comment" {
+		return
+	}
+	samples := m.samples[st.Id]
+	if len(samples) > 0 && time.Since(samples[len(samples)-1].at) < minSampleSpace {
+		// too close to the last one to tell a rate
+		return
+	}
+	samples = append(samples, sample{at: time.Now(), score: st.Score, descendants: st.Descendants})
+	if len(samples) > maxSamples {
+		samples = samples[len(samples)-maxSamples:]
+	}
+	m.samples[st.Id] = samples
+}
+
+// The sparklines of the points and comments per hour of the story, one above
+// the other ("" until it was fetched twice).
+func (m *model) trendView(stId int) string {
+	samples := m.samples[stId]
+	if len(samples) < 2 {
+		return ""
+	}
+	var scores, comments []float64
+	for i := 1; i < len(samples); i++ {
+		hours := samples[i].at.Sub(samples[i-1].at).Hours()
+		if hours <= 0 {
+			continue
+		}
+		scores = append(scores, float64(samples[i].score-samples[i-1].score)/hours)
+		comments = append(comments, float64(samples[i].descendants-samples[i-1].descendants)/hours)
+	}
+	if len(scores) == 0 {
+		return ""
+	}
+	return lipgloss.JoinVertical(lipgloss.Right,
+		style.VoteBar(sparkline(scores)), style.SecondaryStyle.Render(sparkline(comments)))
+}
+
+// Draws the values (negative ones count as 0) relative to the highest.
+func sparkline(values []float64) string {
+	highest := 0.0
+	for _, v := range values {
+		if v > highest {
+			highest = v
+		}
+	}
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if highest > 0 && v > 0 {
+			level = int(v / highest * float64(len(sparks)-1))
+		}
+		line[i] = sparks[level]
+	}
+	return string(line)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"hackerreader/hn"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type userMsg struct {
+	stId int // the pseudo post showing the profile
+	user hn.User
+}
+
+func (m *model) fetchUser(stId int, name string) tea.Cmd {
+	return func() tea.Msg {
+		user, err := m.client.User(context.Background(), name)
+		if err != nil {
+			return errMsg{err: err, retry: m.fetchUser(stId, name)}
+		}
+		return userMsg{stId: stId, user: user}
+	}
+}
+
+// Opens the profile of the given user, with their submissions as its kids.
+func (m *model) openUser(name string) tea.Cmd {
+	st := m.openPseudo("user", name)
+	return m.fetchUser(st.Id, name)
+}
+
+func (m *model) setUser(msg userMsg) {
+	st := m.getPost(msg.stId)
+	st.SetUser(msg.user)
+	if m.selected.Peek().(int) == msg.stId {
+		m.moveCursor(m.cursor)
+	}
+}
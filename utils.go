@@ -13,3 +13,12 @@ func min(a int, b int) int {
 	}
 	return a
 }
+
+func indexOf(ids []int, id int) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"hackerreader/posts"
+)
+
+// Read/unread tracking. While a story is open (it, or one of its comments, is
+// selected), the comments of its thread that make it to the screen are marked
+// as seen. The ones that weren't seen on the previous visits are new: they're
+// highlighted, and the stories show how many of their comments are new.
+
+// The story being read
+type visit struct {
+	stId       int          // 0 => none
+	seenBefore map[int]bool // comments seen on the previous visits (nil => first visit)
+	depth      int          // len of the selected stack when the visit started
+}
+
+// Returns the story the post belongs to (only through posts we already
+// have), or 0 if there's none.
+func (m *model) storyOf(stId int) int {
+	st, exists := m.stories[stId]
+	for exists && st.IsLoaded() && st.Storytype == "comment" {
+		st, exists = m.stories[st.Parent]
+	}
+	if !exists || !st.IsLoaded() || st.Storytype == "pollopt" {
+		return 0
+	}
+	return st.Id
+}
+
+// Starts/ends the visits as the selection changes (called on every update).
+func (m *model) trackVisit() {
+	m.markOnScreen() // before the visit changes: the last frame was about it
+	stId := m.storyOf(m.selected.Peek().(int))
+	if stId == m.visit.stId {
+		return
+	}
+	m.endVisit()
+	if stId == 0 {
+		return
+	}
+	m.visit = visit{
+		stId:       stId,
+		seenBefore: m.visits.Seen(stId),
+		depth:      m.selected.Len(),
+	}
+	m.visits.Visit(stId)
+	// the thread might be (partly) loaded already
+	m.walkThread(stId, m.markNew)
+}
+
+func (m *model) endVisit() {
+	stId := m.visit.stId
+	if stId == 0 {
+		return
+	}
+	m.visit = visit{}
+	if st, exists := m.stories[stId]; exists {
+		m.applyVisits(st)
+	}
+	if err := m.visits.Save(); err != nil {
+		m.err = fmt.Errorf("couldn't save the visits: %w", err)
+	}
+}
+
+// Calls fn for every loaded post under the story (itself excluded), in
+// thread order.
+func (m *model) walkThread(stId int, fn func(st *posts.Post)) {
+	var walk func(stId int)
+	walk = func(stId int) {
+		for _, kidId := range m.stories[stId].Kids {
+			if kid, exists := m.stories[kidId]; exists && kid.IsLoaded() {
+				fn(kid)
+				walk(kidId)
+			}
+		}
+	}
+	if st, exists := m.stories[stId]; exists && st.IsLoaded() {
+		walk(stId)
+	}
+}
+
+// Marks whether the comment (of the story being read) is new.
+func (m *model) markNew(st *posts.Post) {
+	st.IsNew = m.visit.seenBefore != nil && !m.visit.seenBefore[st.Id]
+}
+
+// Marks the comments of the story being read that were on the last frame as
+// seen (the ones only loaded, e.g.: prefetched, weren't).
+func (m *model) markOnScreen() {
+	if m.visit.stId == 0 {
+		return
+	}
+	for _, stId := range *m.onScreen {
+		st, exists := m.stories[stId]
+		if exists && st.IsLoaded() && st.Storytype == "comment" && m.storyOf(stId) == m.visit.stId {
+			m.visits.MarkSeen(m.visit.stId, stId)
+		}
+	}
+}
+
+// Sets what the visits say about the post (when it arrives).
+func (m *model) applyVisits(st *posts.Post) {
+	if st.Storytype == "comment" {
+		if m.visit.stId != 0 && m.storyOf(st.Id) == m.visit.stId {
+			m.markNew(st)
+		}
+		return
+	}
+	_, st.Visited = m.visits.LastVisit(st.Id)
+	if st.Visited && st.Id != m.visit.stId {
+		// the story being read keeps the count it had when it was opened
+		st.NewComments = max(0, st.Descendants-m.visits.SeenCount(st.Id))
+	}
+}
+
+// Jumps to the next new comment of the story being read (after the hovered
+// post, wrapping around).
+func (m *model) nextNew() {
+	if m.visit.stId == 0 {
+		return
+	}
+	var thread []int
+	m.walkThread(m.visit.stId, func(st *posts.Post) {
+		thread = append(thread, st.Id)
+	})
+	start := -1
+	if hoveredId, ok := m.hoveredId(); ok {
+		start = indexOf(thread, hoveredId)
+	}
+	for n := 1; n <= len(thread); n++ {
+		stId := thread[(start+n)%len(thread)]
+		if m.stories[stId].IsNew {
+			m.showPost(m.visit.stId, stId, &m.visit.depth)
+			return
+		}
+	}
+	m.notice = "No new comments"
+}
@@ -0,0 +1,169 @@
+package visits
+
+import (
+	"encoding/json"
+	"errors"
+	"hackerreader/files"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// The stories that were read: for each of them, when it was last visited and
+// which of its comments were seen. It's saved as JSON, e.g.:
+//
+//	{"30377425": {"last": 1645000000, "seen": [30377501, 30377623]}}
+//
+// Stories not visited in a while are forgotten (HN threads are long dead by
+// then). A nil *Visits is valid and remembers nothing.
+
+const maxAge = 90 * 24 * time.Hour
+
+type story struct {
+	last int64 // unix time of the last visit
+	seen map[int]bool
+}
+
+type storyJSON struct {
+	Last int64 `json:"last"`
+	Seen []int `json:"seen"`
+}
+
+type Visits struct {
+	path    string
+	stories map[int]*story
+	dirty   bool // changed since it was saved
+}
+
+// DefaultPath is $XDG_STATE_HOME/hackerreader/visits.json
+// (~/.local/state/hackerreader/visits.json if it isn't set).
+func DefaultPath() (string, error) {
+	return files.StatePath("visits.json")
+}
+
+// Load reads the visits at path. A missing file means no visits yet (it's
+// created when saved).
+func Load(path string) (*Visits, error) {
+	v := &Visits{path: path, stories: make(map[int]*story)}
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var stories map[int]storyJSON
+	if err = json.Unmarshal(data, &stories); err != nil {
+		return nil, err
+	}
+	oldest := time.Now().Add(-maxAge).Unix()
+	for stId, s := range stories {
+		if s.Last < oldest {
+			v.dirty = true
+			continue
+		}
+		seen := make(map[int]bool, len(s.Seen))
+		for _, id := range s.Seen {
+			seen[id] = true
+		}
+		v.stories[stId] = &story{last: s.Last, seen: seen}
+	}
+	return v, nil
+}
+
+// Visit records a visit of the story (now).
+func (v *Visits) Visit(stId int) {
+	if v == nil {
+		return
+	}
+	s, exists := v.stories[stId]
+	if !exists {
+		s = &story{seen: make(map[int]bool)}
+		v.stories[stId] = s
+	}
+	s.last = time.Now().Unix()
+	v.dirty = true
+}
+
+// LastVisit returns when the story was last visited (if it ever was).
+func (v *Visits) LastVisit(stId int) (time.Time, bool) {
+	if v == nil {
+		return time.Time{}, false
+	}
+	s, exists := v.stories[stId]
+	if !exists {
+		return time.Time{}, false
+	}
+	return time.Unix(s.last, 0), true
+}
+
+// MarkSeen records that the comments (of the story) were seen.
+func (v *Visits) MarkSeen(stId int, ids ...int) {
+	if v == nil {
+		return
+	}
+	s, exists := v.stories[stId]
+	if !exists {
+		return // not visited
+	}
+	for _, id := range ids {
+		if !s.seen[id] {
+			s.seen[id] = true
+			v.dirty = true
+		}
+	}
+}
+
+// Seen returns the comments of the story that were seen (nil if it was never
+// visited). The set is a copy: it doesn't change with later visits.
+func (v *Visits) Seen(stId int) map[int]bool {
+	if v == nil {
+		return nil
+	}
+	s, exists := v.stories[stId]
+	if !exists {
+		return nil
+	}
+	seen := make(map[int]bool, len(s.seen))
+	for id := range s.seen {
+		seen[id] = true
+	}
+	return seen
+}
+
+// SeenCount returns how many comments of the story were seen.
+func (v *Visits) SeenCount(stId int) int {
+	if v == nil {
+		return 0
+	}
+	if s, exists := v.stories[stId]; exists {
+		return len(s.seen)
+	}
+	return 0
+}
+
+// Save writes the visits to their file (if anything changed).
+func (v *Visits) Save() error {
+	if v == nil || !v.dirty {
+		return nil
+	}
+	stories := make(map[int]storyJSON, len(v.stories))
+	for stId, s := range v.stories {
+		seen := make([]int, 0, len(s.seen))
+		for id := range s.seen {
+			seen = append(seen, id)
+		}
+		sort.Ints(seen)
+		stories[stId] = storyJSON{Last: s.last, Seen: seen}
+	}
+	data, err := json.Marshal(stories)
+	if err != nil {
+		return err
+	}
+	if err = files.WriteAtomic(v.path, data); err != nil {
+		return err
+	}
+	v.dirty = false
+	return nil
+}